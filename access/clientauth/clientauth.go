@@ -0,0 +1,74 @@
+// Package clientauth provides a small httprouter middleware that access
+// plugins' webhook servers use to require a verified client identity on
+// top of (or instead of) their own protocol-specific checks (Slack
+// signatures, JIRA source-IP allowlists, ...). It covers the case where a
+// plugin is fronted by an mTLS-terminating proxy: TLS-level client
+// certificate verification happens automatically once
+// utils.HTTPConfig.ClientCA is set (see utils.HTTP.tlsConfig), so the only
+// thing left to check at the application layer is that the proxy tells us
+// who the verified client was.
+package clientauth
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config configures the header-based half of client authentication: a
+// header whose value (typically the verified client certificate's
+// Distinguished Name, set by an upstream mTLS-terminating proxy) must
+// match TrustedDNPattern.
+type Config struct {
+	// TrustedDNHeader is the name of the header to check, e.g.
+	// "X-Forwarded-Client-Cert-DN". Leave empty to disable this check.
+	TrustedDNHeader string `yaml:"trusted-dn-header"`
+	// TrustedDNPattern is a regexp the header's value must match in full
+	// (Middleware anchors it automatically), e.g.
+	// `CN=trusted-proxy\.example\.com`. Without full-match anchoring, a
+	// pattern like that would also accept
+	// "X-Evil-CN=trusted-proxy.example.com-attacker" from an upstream
+	// that forwards an unsanitized header verbatim.
+	TrustedDNPattern string `yaml:"trusted-dn-pattern"`
+}
+
+// Enabled reports whether header-based client auth is configured.
+func (c Config) Enabled() bool {
+	return c.TrustedDNHeader != ""
+}
+
+// Middleware wraps a handler so that requests lacking a TrustedDNHeader
+// value matching TrustedDNPattern are rejected with 401 before reaching
+// it. If conf isn't Enabled, it returns next unchanged: plugins that only
+// want TLS-level client certificate verification (via HTTPConfig.ClientCA)
+// don't pay for a check they didn't ask for.
+func Middleware(conf Config, next httprouter.Handle) (httprouter.Handle, error) {
+	if !conf.Enabled() {
+		return next, nil
+	}
+
+	// Anchor the pattern to a full match: otherwise Go's regexp does an
+	// unanchored substring search, and an operator-supplied pattern with
+	// no ^/$ of its own would match a header value that merely contains
+	// it, not one that equals it.
+	pattern, err := regexp.Compile(`^(?:` + conf.TrustedDNPattern + `)$`)
+	if err != nil {
+		return nil, trace.Wrap(err, "invalid TrustedDNPattern")
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		value := r.Header.Get(conf.TrustedDNHeader)
+		if value == "" || !pattern.MatchString(value) {
+			log.WithFields(log.Fields{
+				"header": conf.TrustedDNHeader,
+				"value":  value,
+			}).Error("Rejecting request: trusted client identity header missing or not allowed")
+			http.Error(rw, "", http.StatusUnauthorized)
+			return
+		}
+		next(rw, r, p)
+	}, nil
+}
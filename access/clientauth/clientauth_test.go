@@ -0,0 +1,93 @@
+package clientauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestClientAuth(t *testing.T) { TestingT(t) }
+
+type ClientAuthSuite struct{}
+
+var _ = Suite(&ClientAuthSuite{})
+
+func okHandle(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (s *ClientAuthSuite) TestDisabledPassesThrough(c *C) {
+	handle, err := Middleware(Config{}, okHandle)
+	c.Assert(err, IsNil)
+
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest("POST", "/", nil), nil)
+	c.Assert(rec.Code, Equals, http.StatusOK)
+}
+
+func (s *ClientAuthSuite) TestValidHeaderPasses(c *C) {
+	handle, err := Middleware(Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `^CN=teleport-plugin\.example\.com$`,
+	}, okHandle)
+	c.Assert(err, IsNil)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-Client-Cert-DN", "CN=teleport-plugin.example.com")
+	rec := httptest.NewRecorder()
+	handle(rec, req, nil)
+	c.Assert(rec.Code, Equals, http.StatusOK)
+}
+
+func (s *ClientAuthSuite) TestInvalidHeaderRejected(c *C) {
+	handle, err := Middleware(Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `^CN=teleport-plugin\.example\.com$`,
+	}, okHandle)
+	c.Assert(err, IsNil)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-Client-Cert-DN", "CN=some-other-client")
+	rec := httptest.NewRecorder()
+	handle(rec, req, nil)
+	c.Assert(rec.Code, Equals, http.StatusUnauthorized)
+}
+
+func (s *ClientAuthSuite) TestMissingHeaderRejected(c *C) {
+	handle, err := Middleware(Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `^CN=teleport-plugin\.example\.com$`,
+	}, okHandle)
+	c.Assert(err, IsNil)
+
+	rec := httptest.NewRecorder()
+	handle(rec, httptest.NewRequest("POST", "/", nil), nil)
+	c.Assert(rec.Code, Equals, http.StatusUnauthorized)
+}
+
+func (s *ClientAuthSuite) TestInvalidPatternErrors(c *C) {
+	_, err := Middleware(Config{TrustedDNHeader: "X-DN", TrustedDNPattern: "("}, okHandle)
+	c.Assert(err, NotNil)
+}
+
+// TestUnanchoredPatternRejectsSubstringMatch guards against a proxy that
+// forwards an unsanitized header: an operator-supplied pattern with no
+// ^/$ of its own must still require a full match, not merely that the
+// header value contains it.
+func (s *ClientAuthSuite) TestUnanchoredPatternRejectsSubstringMatch(c *C) {
+	handle, err := Middleware(Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `CN=trusted-proxy\.example\.com`,
+	}, okHandle)
+	c.Assert(err, IsNil)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-Forwarded-Client-Cert-DN", "X-Evil-CN=trusted-proxy.example.com-attacker")
+	rec := httptest.NewRecorder()
+	handle(rec, req, nil)
+	c.Assert(rec.Code, Equals, http.StatusUnauthorized)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gravitational/teleport-plugins/access"
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// pingInterval is how often Run polls GitLab's health via Bot.Ping, so the
+// plugin's reported status reflects reality even while no access request
+// is pending to otherwise exercise the API.
+const pingInterval = 5 * time.Minute
+
+// App is the GitLab access-request plugin process: it watches Teleport
+// for pending access requests, opens a GitLab issue for each one, and
+// watches GitLab's system webhook for approved/denied label transitions
+// to drive them to a final state. It mirrors access/jirabot's App.
+type App struct {
+	conf Config
+
+	client     access.Client
+	bot        *Bot
+	webhookSrv *WebhookServer
+	statusSink status.Sink
+
+	readyCh chan struct{}
+	doneCh  chan struct{}
+	runErr  error
+}
+
+// NewApp builds an App from conf, but does not start it; call Run to do
+// that.
+func NewApp(conf Config) (*App, error) {
+	bot, err := NewBot(&conf)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app := &App{
+		conf:    conf,
+		bot:     bot,
+		readyCh: make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+
+	webhookSrv, err := NewWebhookServer(conf.HTTP, conf.GitLab.WebhookSecret, app.onWebhook)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app.webhookSrv = webhookSrv
+
+	return app, nil
+}
+
+// PublicURL is the URL operators must register with GitLab's system
+// webhook settings.
+func (a *App) PublicURL() *url.URL {
+	return a.webhookSrv.BaseURL()
+}
+
+// WaitReady blocks until the App has finished starting up (or ctx is
+// done), returning whether it's actually ready.
+func (a *App) WaitReady(ctx context.Context) (bool, error) {
+	select {
+	case <-a.readyCh:
+		return true, nil
+	case <-a.doneCh:
+		return false, a.runErr
+	case <-ctx.Done():
+		return false, trace.Wrap(ctx.Err())
+	}
+}
+
+// Shutdown stops the App and waits for Run to return.
+func (a *App) Shutdown(ctx context.Context) error {
+	select {
+	case <-a.doneCh:
+		return a.runErr
+	case <-ctx.Done():
+		return trace.Wrap(ctx.Err())
+	}
+}
+
+// Run starts the App: it connects to Teleport, wires up the default
+// status sink, starts the webhook server, and watches for pending access
+// requests until ctx is canceled.
+func (a *App) Run(ctx context.Context) error {
+	defer close(a.doneCh)
+
+	tc, err := access.LoadTLSConfig(a.conf.Teleport.ClientCrt, a.conf.Teleport.ClientKey, a.conf.Teleport.RootCAs)
+	if err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+
+	client, err := access.NewClient(ctx, a.conf.Teleport.AuthServer, tc)
+	if err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+	a.client = client
+
+	a.statusSink = status.NewDefaultSink("gitlab", client)
+	a.bot.SetStatusSink(a.statusSink)
+	a.webhookSrv.SetStatusSink(a.statusSink)
+
+	watcher, err := client.WatchRequests(ctx, access.Filter{State: access.StatePending})
+	if err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+	defer watcher.Close()
+
+	if err := a.webhookSrv.EnsureCert(); err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+
+	httpJob := a.webhookSrv.ServiceJob()
+	httpErrCh := make(chan error, 1)
+	go func() { httpErrCh <- httpJob(ctx) }()
+
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	close(a.readyCh)
+
+	for {
+		select {
+		case event := <-watcher.Events():
+			if err := a.handleEvent(ctx, event); err != nil {
+				a.runErr = trace.Wrap(err)
+				return a.runErr
+			}
+		case err := <-httpErrCh:
+			a.runErr = trace.Wrap(err)
+			return a.runErr
+		case <-watcher.Done():
+			a.runErr = trace.Wrap(watcher.Error())
+			return a.runErr
+		case <-pingTicker.C:
+			if err := a.Ping(ctx); err != nil {
+				log.WithError(err).Error("Failed to ping GitLab")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Ping performs a lightweight round-trip to GitLab and reports the result
+// to the App's status sink, so a GitLab outage is reflected in plugin
+// status even while no access request is pending to otherwise exercise
+// the API.
+func (a *App) Ping(ctx context.Context) error {
+	return trace.Wrap(a.bot.Ping(ctx))
+}
+
+func (a *App) handleEvent(ctx context.Context, event access.Event) error {
+	req, op := event.Request, event.Type
+	switch op {
+	case access.OpInit:
+		return nil
+	case access.OpPut:
+		return a.handlePendingRequest(ctx, req)
+	case access.OpDelete:
+		return nil
+	default:
+		return trace.BadParameter("unexpected event operation %s", op)
+	}
+}
+
+func (a *App) handlePendingRequest(ctx context.Context, req access.Request) error {
+	data, err := a.bot.CreateIssue(ctx, req.ID, requestData{
+		user:    req.User,
+		roles:   req.Roles,
+		created: req.Created,
+	})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return a.client.UpdatePluginData(ctx, req.ID, encodeGitlabData(data))
+}
+
+// onWebhook reacts to a label change on an issue we created: an
+// "approved" or "denied" label drives the matching Teleport request to
+// that state. It re-fetches the issue by IID rather than trusting
+// webhook.ObjectAttributes.Labels, the way the JIRA bot looks issues up
+// by ID instead of trusting the webhook body's contents: GitLab's system
+// webhook payload isn't guaranteed to carry every label the issue
+// actually has (some event types only list the labels that changed), so
+// the requestLabelPrefix label set at creation time could be missing
+// from the payload even though it's still on the issue.
+func (a *App) onWebhook(ctx context.Context, webhook Webhook) error {
+	issue, err := a.bot.GetIssue(ctx, gitlabData{IssueIID: webhook.ObjectAttributes.IID})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	reqID, err := issue.GetRequestID()
+	if err != nil {
+		// Not an issue we created; ignore the webhook rather than erroring
+		// the whole delivery.
+		return nil
+	}
+
+	var state access.State
+	switch {
+	case containsLabel(issue.Labels, approvedLabel):
+		state = access.StateApproved
+	case containsLabel(issue.Labels, deniedLabel):
+		state = access.StateDenied
+	default:
+		return nil
+	}
+
+	return a.client.SetRequestState(ctx, reqID, state, "gitlab")
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeGitlabData(data gitlabData) map[string]string {
+	return map[string]string{
+		"issue_id":  strconv.Itoa(data.IssueID),
+		"issue_iid": strconv.Itoa(data.IssueIID),
+	}
+}
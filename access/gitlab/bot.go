@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	gitlab "github.com/xanzy/go-gitlab"
+
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// requestLabelPrefix tags an issue with the Teleport access request it
+	// was created for, since GitLab issues have no free-form custom
+	// property field the way JIRA ones do.
+	requestLabelPrefix = "teleport-request:"
+
+	approvedLabel = "approved"
+	deniedLabel   = "denied"
+	expiredLabel  = "expired"
+)
+
+// requestData is the subset of an access.AccessRequest the bot needs to
+// render an issue for it.
+type requestData struct {
+	user    string
+	roles   []string
+	created time.Time
+}
+
+// gitlabData is what the plugin stashes in Teleport's plugin-data store
+// for a request, so it can find the issue it created for it again later.
+type gitlabData struct {
+	IssueID  int
+	IssueIID int
+}
+
+// Issue is a thin wrapper around gitlab.Issue that knows how to recover
+// the Teleport request ID it was created for.
+type Issue gitlab.Issue
+
+// GetRequestID extracts the Teleport access request ID from the issue's
+// "teleport-request:<id>" label.
+func (issue *Issue) GetRequestID() (string, error) {
+	for _, label := range issue.Labels {
+		if id := tryTrimLabel(label); id != "" {
+			return id, nil
+		}
+	}
+	return "", trace.Errorf("issue has no %s label", requestLabelPrefix)
+}
+
+func tryTrimLabel(label string) string {
+	if len(label) > len(requestLabelPrefix) && label[:len(requestLabelPrefix)] == requestLabelPrefix {
+		return label[len(requestLabelPrefix):]
+	}
+	return ""
+}
+
+// Bot is a wrapper around gitlab.Client that works with access.Request,
+// mirroring the jirabot.Bot design.
+type Bot struct {
+	client     *gitlab.Client
+	projectID  string
+	statusSink status.Sink
+}
+
+// NewBot builds a Bot from the GitLab portion of Config.
+func NewBot(conf *Config) (*Bot, error) {
+	client, err := gitlab.NewClient(conf.GitLab.Token, gitlab.WithBaseURL(conf.GitLab.URL))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Bot{
+		client:    client,
+		projectID: conf.GitLab.ProjectID,
+	}, nil
+}
+
+// SetStatusSink wires a status.Sink that the bot will report its health
+// to on every call to the GitLab API, deduplicated so a sustained outage
+// doesn't spam the sink with identical reports.
+func (b *Bot) SetStatusSink(sink status.Sink) {
+	b.statusSink = status.NewDedup(sink)
+}
+
+// Ping performs a lightweight round-trip to GitLab (GET /user) and
+// reports the result to the configured status sink, if any.
+func (b *Bot) Ping(ctx context.Context) error {
+	_, res, err := b.client.Users.CurrentUser()
+	b.reportStatus(ctx, res, err)
+	return trace.Wrap(err)
+}
+
+func (b *Bot) reportStatus(ctx context.Context, res *gitlab.Response, err error) {
+	if b.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{LastReported: time.Now()}
+	switch {
+	case err == nil:
+		st.Code = status.Running
+	case res != nil && (res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden):
+		st.Code = status.Unauthorized
+		st.LastError = err.Error()
+	case res != nil:
+		st.Code = status.FromHTTPStatusCode(res.StatusCode)
+		st.LastError = err.Error()
+	default:
+		st.Code = status.OtherError
+		st.LastError = err.Error()
+	}
+	if reportErr := b.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
+
+// CreateIssue creates an issue tagged with reqID, so the plugin can find
+// it again when the request is resolved or expires.
+func (b *Bot) CreateIssue(ctx context.Context, reqID string, reqData requestData) (gitlabData, error) {
+	issue, res, err := b.client.Issues.CreateIssue(b.projectID, &gitlab.CreateIssueOptions{
+		Title:       gitlab.String(fmt.Sprintf("Access request from %s", reqData.user)),
+		Description: gitlab.String(fmt.Sprintf("%s is requesting roles %v", reqData.user, reqData.roles)),
+		Labels:      []string{requestLabelPrefix + reqID},
+	})
+	b.reportStatus(ctx, res, err)
+	if err != nil {
+		return gitlabData{}, trace.Wrap(err)
+	}
+	return gitlabData{IssueID: issue.ID, IssueIID: issue.IID}, nil
+}
+
+// GetIssue fetches the issue the plugin created for a request.
+func (b *Bot) GetIssue(ctx context.Context, data gitlabData) (*Issue, error) {
+	issue, res, err := b.client.Issues.GetIssue(b.projectID, data.IssueIID)
+	b.reportStatus(ctx, res, err)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := Issue(*issue)
+	return &result, nil
+}
+
+// ExpireIssue labels an issue as expired, mirroring
+// jirabot.Bot.ExpireIssue's "Expired" transition. Like that method (and
+// slack.Bot.ExpireMessage), it's not currently wired to anything: App's
+// handleEvent treats access.OpDelete as a no-op, so this is a hook
+// waiting on watch-event support for expiry, not a live path.
+func (b *Bot) ExpireIssue(ctx context.Context, reqID string, data gitlabData) error {
+	issue, err := b.GetIssue(ctx, data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, res, err := b.client.Issues.UpdateIssue(b.projectID, data.IssueIID, &gitlab.UpdateIssueOptions{
+		Labels: append(issue.Labels, expiredLabel),
+	})
+	b.reportStatus(ctx, res, err)
+	return trace.Wrap(err)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"github.com/gravitational/teleport-plugins/utils"
+	"github.com/gravitational/trace"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultDir is the default directory the GitLab plugin keeps generated
+// certificates (see utils.HTTP.EnsureCert) in.
+const DefaultDir = "/var/lib/teleport/plugins/gitlab"
+
+// Config is the GitLab access-request plugin's configuration, loaded from
+// the YAML file passed on the command line.
+type Config struct {
+	Teleport struct {
+		AuthServer string `yaml:"auth-server"`
+		ClientCrt  string `yaml:"client-crt"`
+		ClientKey  string `yaml:"client-key"`
+		RootCAs    string `yaml:"root-cas"`
+	} `yaml:"teleport"`
+	GitLab struct {
+		URL           string `yaml:"url"`
+		Token         string `yaml:"token"`
+		ProjectID     string `yaml:"project-id"`
+		WebhookSecret string `yaml:"webhook-secret"`
+	} `yaml:"gitlab"`
+	HTTP utils.HTTPConfig `yaml:"http"`
+}
+
+// LoadConfig reads and parses a Config from the YAML file at path.
+func LoadConfig(path string) (*Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var conf Config
+	if err := yaml.Unmarshal(bytes, &conf); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if conf.GitLab.URL == "" {
+		conf.GitLab.URL = "https://gitlab.com"
+	}
+	if conf.GitLab.WebhookSecret == "" {
+		return nil, trace.BadParameter("gitlab.webhook-secret must be set")
+	}
+	return &conf, nil
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/gravitational/teleport-plugins/access/integration"
+	"github.com/gravitational/teleport-plugins/utils"
+	"github.com/gravitational/teleport/lib/auth/testauthority"
+	"github.com/gravitational/teleport/lib/events"
+	"github.com/gravitational/teleport/lib/services"
+
+	. "gopkg.in/check.v1"
+)
+
+const (
+	Host          = "localhost"
+	HostID        = "00000000-0000-0000-0000-000000000000"
+	Site          = "local-site"
+	WebhookSecret = "1234567890"
+	ProjectID     = "1"
+)
+
+type GitlabSuite struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	appConfig  Config
+	app        *App
+	publicURL  string
+	me         *user.User
+	fakeGitlab *fakeGitlabServer
+	teleport   *integration.TeleInstance
+	tmpFiles   []*os.File
+}
+
+var _ = Suite(&GitlabSuite{})
+
+func TestGitlabbot(t *testing.T) { TestingT(t) }
+
+func (s *GitlabSuite) SetUpSuite(c *C) {
+	var err error
+	log.SetLevel(log.DebugLevel)
+	priv, pub, err := testauthority.New().GenerateKeyPair("")
+	c.Assert(err, IsNil)
+	t := integration.NewInstance(integration.InstanceConfig{ClusterName: Site, HostID: HostID, NodeName: Host, Priv: priv, Pub: pub})
+
+	s.me, err = user.Current()
+	c.Assert(err, IsNil)
+	userRole, err := services.NewRole("foo", services.RoleSpecV3{
+		Allow: services.RoleConditions{
+			Logins:  []string{s.me.Username}, // cannot be empty
+			Request: &services.AccessRequestConditions{Roles: []string{"admin"}},
+		},
+	})
+	c.Assert(err, IsNil)
+	t.AddUserWithRole(s.me.Username, userRole)
+
+	accessPluginRole, err := services.NewRole("access-plugin", services.RoleSpecV3{
+		Allow: services.RoleConditions{
+			Logins: []string{"access-plugin"}, // cannot be empty
+			Rules: []services.Rule{
+				services.NewRule("access_request", []string{"list", "read", "update"}),
+			},
+		},
+	})
+	c.Assert(err, IsNil)
+	t.AddUserWithRole("plugin", accessPluginRole)
+
+	err = t.Create(nil, nil)
+	c.Assert(err, IsNil)
+	if err := t.Start(); err != nil {
+		c.Fatalf("Unexpected response from Start: %v", err)
+	}
+	s.teleport = t
+}
+
+func (s *GitlabSuite) SetUpTest(c *C) {
+	s.ctx, s.cancel = context.WithTimeout(context.Background(), time.Second)
+	s.publicURL = ""
+	s.fakeGitlab = newFakeGitlabServer()
+
+	auth := s.teleport.Process.GetAuthServer()
+	certAuthorities, err := auth.GetCertAuthorities(services.HostCA, false)
+	c.Assert(err, IsNil)
+	pluginKey := s.teleport.Secrets.Users["plugin"].Key
+
+	keyFile := s.newTmpFile(c, "auth.*.key")
+	_, err = keyFile.Write(pluginKey.Priv)
+	c.Assert(err, IsNil)
+	keyFile.Close()
+
+	certFile := s.newTmpFile(c, "auth.*.crt")
+	_, err = certFile.Write(pluginKey.TLSCert)
+	c.Assert(err, IsNil)
+	certFile.Close()
+
+	casFile := s.newTmpFile(c, "auth.*.cas")
+	for _, ca := range certAuthorities {
+		for _, keyPair := range ca.GetTLSKeyPairs() {
+			_, err = casFile.Write(keyPair.Cert)
+			c.Assert(err, IsNil)
+		}
+	}
+	casFile.Close()
+
+	authAddr, err := s.teleport.Process.AuthSSHAddr()
+	c.Assert(err, IsNil)
+
+	var conf Config
+	conf.Teleport.AuthServer = authAddr.Addr
+	conf.Teleport.ClientCrt = certFile.Name()
+	conf.Teleport.ClientKey = keyFile.Name()
+	conf.Teleport.RootCAs = casFile.Name()
+	conf.GitLab.URL = s.fakeGitlab.URL()
+	conf.GitLab.Token = "000000"
+	conf.GitLab.ProjectID = ProjectID
+	conf.GitLab.WebhookSecret = WebhookSecret
+	conf.HTTP.ListenAddr = ":0"
+	conf.HTTP.Insecure = true
+
+	s.appConfig = conf
+}
+
+func (s *GitlabSuite) TearDownTest(c *C) {
+	s.shutdownApp(c)
+	s.fakeGitlab.Close()
+	s.cancel()
+	for _, tmp := range s.tmpFiles {
+		err := os.Remove(tmp.Name())
+		c.Assert(err, IsNil)
+	}
+	s.tmpFiles = []*os.File{}
+}
+
+func (s *GitlabSuite) newTmpFile(c *C, pattern string) (file *os.File) {
+	file, err := ioutil.TempFile("", pattern)
+	c.Assert(err, IsNil)
+	s.tmpFiles = append(s.tmpFiles, file)
+	return
+}
+
+func (s *GitlabSuite) startApp(c *C) {
+	var err error
+
+	if s.publicURL != "" {
+		s.appConfig.HTTP.PublicAddr = s.publicURL
+	}
+	s.app, err = NewApp(s.appConfig)
+	c.Assert(err, IsNil)
+
+	go func() {
+		err = s.app.Run(s.ctx)
+		c.Assert(err, IsNil)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*250)
+	defer cancel()
+	ok, err := s.app.WaitReady(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	if s.publicURL == "" {
+		s.publicURL = s.app.PublicURL().String()
+	}
+}
+
+func (s *GitlabSuite) shutdownApp(c *C) {
+	err := s.app.Shutdown(s.ctx)
+	c.Assert(err, IsNil)
+}
+
+func (s *GitlabSuite) createAccessRequest(c *C) services.AccessRequest {
+	req, err := s.teleport.CreateAccessRequest(s.ctx, s.me.Username, "admin")
+	c.Assert(err, IsNil)
+	return req
+}
+
+func (s *GitlabSuite) checkPluginData(c *C, reqID string) gitlabData {
+	rawData, err := s.teleport.PollAccessRequestPluginData(s.ctx, "gitlab", reqID)
+	c.Assert(err, IsNil)
+	issueID, err := strconv.Atoi(rawData["issue_id"])
+	c.Assert(err, IsNil)
+	issueIID, err := strconv.Atoi(rawData["issue_iid"])
+	c.Assert(err, IsNil)
+	return gitlabData{IssueID: issueID, IssueIID: issueIID}
+}
+
+// postWebhook delivers a GitLab "Issue Hook" payload naming issueIID to
+// the plugin's webhook server, signed with WebhookSecret. Real GitLab
+// issue-hook deliveries aren't guaranteed to carry every label the issue
+// currently has (some event types only list the labels that changed), so
+// onWebhook re-fetches the issue by IID rather than trusting the
+// payload's own label list — this helper reflects that by sending none.
+func (s *GitlabSuite) postWebhook(c *C, issueIID int) *http.Response {
+	payload := struct {
+		ObjectKind       string `json:"object_kind"`
+		ObjectAttributes struct {
+			IID int `json:"iid"`
+		} `json:"object_attributes"`
+	}{ObjectKind: "issue"}
+	payload.ObjectAttributes.IID = issueIID
+
+	body, err := json.Marshal(payload)
+	c.Assert(err, IsNil)
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.publicURL, bytes.NewReader(body))
+	c.Assert(err, IsNil)
+	req.Header.Set("X-Gitlab-Token", WebhookSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	return resp
+}
+
+func (s *GitlabSuite) TestIssueGetRequestID(c *C) {
+	issue := Issue{Labels: []string{"bug", requestLabelPrefix + "req-42"}}
+	reqID, err := issue.GetRequestID()
+	c.Assert(err, IsNil)
+	c.Assert(reqID, Equals, "req-42")
+}
+
+func (s *GitlabSuite) TestIssueGetRequestIDMissing(c *C) {
+	issue := Issue{Labels: []string{"bug"}}
+	_, err := issue.GetRequestID()
+	c.Assert(err, NotNil)
+}
+
+func (s *GitlabSuite) TestWebhookRejectsBadToken(c *C) {
+	srv, err := NewWebhookServer(utils.HTTPConfig{ListenAddr: ":0", Insecure: true}, "the-secret", func(ctx context.Context, webhook Webhook) error {
+		c.Fatalf("onWebhook should not be called for an invalid token")
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("X-Gitlab-Token", "wrong-secret")
+	srv.processWebhook(rec, req, nil)
+	c.Assert(rec.Code, Equals, 401)
+}
+
+// TestWebhookRejectsEmptySecret guards against the constant-time
+// comparison in processWebhook trivially matching an empty header
+// against an empty configured secret.
+func (s *GitlabSuite) TestWebhookRejectsEmptySecret(c *C) {
+	_, err := NewWebhookServer(utils.HTTPConfig{ListenAddr: ":0", Insecure: true}, "", func(ctx context.Context, webhook Webhook) error {
+		c.Fatalf("onWebhook should not be called")
+		return nil
+	})
+	c.Assert(err, NotNil)
+}
+
+func (s *GitlabSuite) TestLoadConfigRejectsEmptyWebhookSecret(c *C) {
+	confFile := s.newTmpFile(c, "gitlab.*.yaml")
+	_, err := confFile.WriteString(`
+teleport:
+  auth-server: "example.com:3025"
+gitlab:
+  url: "https://gitlab.example.com"
+  token: "xyz"
+  project-id: "1"
+`)
+	c.Assert(err, IsNil)
+	confFile.Close()
+
+	_, err = LoadConfig(confFile.Name())
+	c.Assert(err, NotNil)
+}
+
+// TestIssueCreationAndApproval exercises the full flow: a pending access
+// request creates a GitLab issue, a reviewer adds the "approved" label
+// (simulated via the fake server), and the resulting webhook delivery
+// drives the Teleport request to the approved state.
+func (s *GitlabSuite) TestIssueCreationAndApproval(c *C) {
+	s.startApp(c)
+	request := s.createAccessRequest(c)
+	pluginData := s.checkPluginData(c, request.GetName())
+
+	issue, ok := s.fakeGitlab.GetIssue(pluginData.IssueIID)
+	c.Assert(ok, Equals, true)
+	c.Assert(issue.Labels, Contains, requestLabelPrefix+request.GetName())
+
+	s.fakeGitlab.AddLabel(pluginData.IssueIID, approvedLabel)
+	resp := s.postWebhook(c, pluginData.IssueIID)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	request, err := s.teleport.GetAccessRequest(s.ctx, request.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(request.GetState(), Equals, services.RequestState_APPROVED)
+
+	auditLog, err := s.teleport.FilterAuditEvents("", events.EventFields{"event": events.AccessRequestUpdated.Name, "id": request.GetName()})
+	c.Assert(err, IsNil)
+	c.Assert(auditLog, HasLen, 1)
+	c.Assert(auditLog[0].GetString("state"), Equals, "APPROVED")
+	c.Assert(auditLog[0].GetString("delegator"), Equals, "gitlab")
+}
+
+func (s *GitlabSuite) TestDenial(c *C) {
+	s.startApp(c)
+	request := s.createAccessRequest(c)
+	pluginData := s.checkPluginData(c, request.GetName())
+
+	s.fakeGitlab.AddLabel(pluginData.IssueIID, deniedLabel)
+	resp := s.postWebhook(c, pluginData.IssueIID)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	request, err := s.teleport.GetAccessRequest(s.ctx, request.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(request.GetState(), Equals, services.RequestState_DENIED)
+}
+
+// TestWebhookIgnoresUnrelatedIssue checks that a label-change webhook for
+// an issue the plugin didn't create (no teleport-request: label) is
+// accepted but doesn't touch any Teleport request. onWebhook re-fetches
+// the issue by IID, so this also covers that the lookup itself succeeds
+// for an issue that simply isn't ours.
+func (s *GitlabSuite) TestWebhookIgnoresUnrelatedIssue(c *C) {
+	s.startApp(c)
+	s.createAccessRequest(c)
+
+	iid := s.fakeGitlab.CreateIssue("bug", approvedLabel)
+	resp := s.postWebhook(c, iid)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+}
+
+// TestWebhookErrorsOnUnknownIssue checks that a webhook naming an IID the
+// fake GitLab server has never heard of surfaces as a failure (rather
+// than being silently treated as "not ours"), since a 404 from GitLab
+// means something is actually wrong, not that the issue is unrelated.
+func (s *GitlabSuite) TestWebhookErrorsOnUnknownIssue(c *C) {
+	s.startApp(c)
+
+	resp := s.postWebhook(c, 99999)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+}
+
+// TestPing checks that App.Ping (the same call Run makes periodically)
+// succeeds against a reachable GitLab.
+func (s *GitlabSuite) TestPing(c *C) {
+	s.startApp(c)
+	c.Assert(s.app.Ping(s.ctx), IsNil)
+}
+
+// fakeGitlabServer is a minimal stand-in for GitLab's REST API, covering
+// just the endpoints Bot calls: creating/fetching/updating an issue.
+type fakeGitlabServer struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	nextIID int
+	issues  map[int]*Issue
+}
+
+func newFakeGitlabServer() *fakeGitlabServer {
+	f := &fakeGitlabServer{issues: make(map[int]*Issue)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/user", f.handleUser)
+	mux.HandleFunc("/api/v4/projects/", f.handleProjects)
+	f.srv = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeGitlabServer) URL() string { return f.srv.URL }
+func (f *fakeGitlabServer) Close()      { f.srv.Close() }
+
+func (f *fakeGitlabServer) GetIssue(iid int) (*Issue, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	issue, ok := f.issues[iid]
+	return issue, ok
+}
+
+// CreateIssue directly inserts an issue with the given labels, as if it
+// had been filed by hand in GitLab rather than by Bot.CreateIssue, and
+// returns its IID.
+func (f *fakeGitlabServer) CreateIssue(labels ...string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextIID++
+	f.issues[f.nextIID] = &Issue{ID: f.nextIID, IID: f.nextIID, Labels: labels}
+	return f.nextIID
+}
+
+// AddLabel appends label to the issue identified by iid, as a reviewer
+// clicking GitLab's label picker would.
+func (f *fakeGitlabServer) AddLabel(iid int, label string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	issue := f.issues[iid]
+	issue.Labels = append(issue.Labels, label)
+}
+
+func (f *fakeGitlabServer) handleUser(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(rw, `{"id": 1, "username": "access-plugin"}`)
+}
+
+// handleProjects serves the issues sub-resource: POST creates, GET/PUT
+// fetch or update a single issue by IID.
+func (f *fakeGitlabServer) handleProjects(rw http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/projects/"), "/")
+	if len(parts) < 2 || parts[1] != "issues" {
+		http.NotFound(rw, r)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var body struct {
+			Title       string   `json:"title"`
+			Description string   `json:"description"`
+			Labels      []string `json:"labels"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.nextIID++
+		issue := &Issue{ID: f.nextIID, IID: f.nextIID, Title: body.Title, Labels: body.Labels}
+		f.issues[issue.IID] = issue
+		f.writeIssue(rw, issue)
+
+	case len(parts) == 3:
+		iid, err := strconv.Atoi(parts[2])
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		issue, ok := f.issues[iid]
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+		if r.Method == http.MethodPut {
+			var body struct {
+				Labels []string `json:"labels"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+			issue.Labels = body.Labels
+		}
+		f.writeIssue(rw, issue)
+
+	default:
+		http.NotFound(rw, r)
+	}
+}
+
+func (f *fakeGitlabServer) writeIssue(rw http.ResponseWriter, issue *Issue) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(issue)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/teleport-plugins/utils"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+)
+
+// Webhook is the subset of a GitLab "Issue Hook" system webhook payload
+// the plugin cares about: who changed what labels on which issue.
+type Webhook struct {
+	HTTPRequestID string
+
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		ID int `json:"id"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID     int      `json:"id"`
+		IID    int      `json:"iid"`
+		Action string   `json:"action"`
+		Labels []string `json:"-"`
+	} `json:"object_attributes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+}
+
+type WebhookFunc func(ctx context.Context, webhook Webhook) error
+
+// WebhookServer is a wrapper around http.Server that processes GitLab
+// system webhook events, mirroring jira.WebhookServer. It verifies the
+// X-Gitlab-Token header before calling onWebhook.
+type WebhookServer struct {
+	http      *utils.HTTP
+	secret    string
+	onWebhook WebhookFunc
+	counter   uint64
+
+	statusSink status.Sink
+}
+
+func NewWebhookServer(conf utils.HTTPConfig, secret string, onWebhook WebhookFunc) (*WebhookServer, error) {
+	if secret == "" {
+		// An empty secret would make the constant-time comparison below
+		// accept any request that also omits X-Gitlab-Token, i.e. any
+		// unauthenticated POST to the public webhook URL.
+		return nil, trace.BadParameter("secret must not be empty")
+	}
+	httpSrv, err := utils.NewHTTP(conf)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	srv := &WebhookServer{
+		http:      httpSrv,
+		secret:    secret,
+		onWebhook: onWebhook,
+	}
+	httpSrv.POST("/", srv.processWebhook)
+	return srv, nil
+}
+
+// SetStatusSink wires a status.Sink that the server will report to
+// whenever it fails to process a webhook, deduplicated so a sustained
+// failure doesn't spam the sink with identical reports.
+func (s *WebhookServer) SetStatusSink(sink status.Sink) {
+	s.statusSink = status.NewDedup(sink)
+}
+
+func (s *WebhookServer) ServiceJob() utils.ServiceJob {
+	return s.http.ServiceJob()
+}
+
+func (s *WebhookServer) BaseURL() *url.URL {
+	return s.http.BaseURL()
+}
+
+func (s *WebhookServer) EnsureCert() error {
+	return s.http.EnsureCert(DefaultDir + "/server")
+}
+
+func (s *WebhookServer) processWebhook(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Millisecond*2500)
+	defer cancel()
+
+	httpRequestID := fmt.Sprintf("%v-%v", time.Now().Unix(), atomic.AddUint64(&s.counter, 1))
+	log := log.WithField("gitlab_http_id", httpRequestID)
+
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(s.secret)) != 1 {
+		log.Error("X-Gitlab-Token header does not match configured secret")
+		s.reportStatus(ctx, status.Unauthorized, trace.AccessDenied("invalid X-Gitlab-Token"))
+		http.Error(rw, "", http.StatusUnauthorized)
+		return
+	}
+
+	webhook := Webhook{HTTPRequestID: httpRequestID}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.WithError(err).Error("Failed to read webhook payload")
+		http.Error(rw, "", http.StatusInternalServerError)
+		return
+	}
+	if err = json.Unmarshal(body, &webhook); err != nil {
+		log.WithError(err).Error("Failed to parse webhook payload")
+		http.Error(rw, "", http.StatusBadRequest)
+		return
+	}
+	for _, label := range webhook.Labels {
+		webhook.ObjectAttributes.Labels = append(webhook.ObjectAttributes.Labels, label.Title)
+	}
+
+	if err = s.onWebhook(ctx, webhook); err != nil {
+		log.WithError(err).Error("Failed to process webhook")
+		log.Debugf("%v", trace.DebugReport(err))
+		var code int
+		switch {
+		case utils.IsCanceled(err) || utils.IsDeadline(err):
+			code = http.StatusServiceUnavailable
+		default:
+			code = http.StatusInternalServerError
+		}
+		s.reportStatus(ctx, status.FromHTTPStatusCode(code), err)
+		http.Error(rw, "", code)
+	} else {
+		s.reportStatus(ctx, status.Running, nil)
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *WebhookServer) reportStatus(ctx context.Context, code status.Code, err error) {
+	if s.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{Code: code, LastReported: time.Now()}
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	if reportErr := s.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/trace"
+	log "github.com/sirupsen/logrus"
+)
+
+// requestIDPropertyKey is the JIRA issue property that carries the
+// Teleport access request ID an issue was created for.
+const requestIDPropertyKey = "teleportAccessRequestId"
+
+const (
+	jiraMaxConns    = 100
+	jiraHTTPTimeout = 10 * time.Second
+)
+
+// requestData is the subset of an access.AccessRequest the bot needs to
+// render an issue for it.
+type requestData struct {
+	user    string
+	roles   []string
+	created time.Time
+}
+
+// jiraData is what the plugin stashes in Teleport's plugin-data store for
+// a request, so it can find the issue it created for it again later.
+type jiraData struct {
+	ID  string
+	Key string
+}
+
+// Issue is a thin wrapper around jira.Issue that knows how to recover the
+// Teleport request ID it was created for.
+type Issue jira.Issue
+
+// GetRequestID extracts the Teleport access request ID from the issue's
+// requestIDPropertyKey property.
+func (issue *Issue) GetRequestID() (string, error) {
+	reqID, ok := issue.Properties[requestIDPropertyKey].(string)
+	if !ok {
+		return "", trace.Errorf("got non-string %q property", requestIDPropertyKey)
+	}
+	return reqID, nil
+}
+
+// GetTransition finds the transition that would move the issue to
+// toStatus, among the transitions JIRA reports as currently available.
+func (issue *Issue) GetTransition(toStatus string) (jira.Transition, error) {
+	for _, transition := range issue.Transitions {
+		if strings.ToLower(transition.To.Name) == toStatus {
+			return transition, nil
+		}
+	}
+	return jira.Transition{}, trace.Errorf("cannot find a %q status among possible transitions", toStatus)
+}
+
+// Bot is a wrapper around jira.Client that works with access.Request.
+type Bot struct {
+	client     *jira.Client
+	project    string
+	statusSink status.Sink
+}
+
+// NewBot builds a Bot from the JIRA portion of Config.
+func NewBot(conf *Config) (*Bot, error) {
+	transport := jira.BasicAuthTransport{
+		Username: conf.JIRA.Username,
+		Password: conf.JIRA.APIToken,
+		Transport: &http.Transport{
+			MaxConnsPerHost:     jiraMaxConns,
+			MaxIdleConnsPerHost: jiraMaxConns,
+		},
+	}
+	httpClient := transport.Client()
+	httpClient.Timeout = jiraHTTPTimeout
+
+	client, err := jira.NewClient(httpClient, conf.JIRA.URL)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &Bot{
+		client:  client,
+		project: conf.JIRA.Project,
+	}, nil
+}
+
+// SetStatusSink wires a status.Sink that the bot will report its health to
+// on every call to the JIRA API, deduplicated so a sustained outage
+// doesn't spam the sink with identical reports.
+func (b *Bot) SetStatusSink(sink status.Sink) {
+	b.statusSink = status.NewDedup(sink)
+}
+
+// Ping performs a lightweight round-trip to JIRA (GET /rest/api/2/myself)
+// and reports the result to the configured status sink, if any.
+func (b *Bot) Ping(ctx context.Context) error {
+	_, res, err := b.client.User.GetSelf()
+	b.reportStatus(ctx, res, err)
+	return trace.Wrap(err)
+}
+
+// reportStatus classifies err (and, if available, the JIRA response that
+// produced it) and pushes the result to the bot's status sink. It's a
+// no-op when no sink is configured, so callers can use it unconditionally.
+func (b *Bot) reportStatus(ctx context.Context, res *jira.Response, err error) {
+	if b.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{LastReported: time.Now()}
+	switch {
+	case err == nil:
+		st.Code = status.Running
+	case res != nil:
+		st.Code = status.FromHTTPStatusCode(res.StatusCode)
+		st.LastError = err.Error()
+	default:
+		st.Code = status.OtherError
+		st.LastError = err.Error()
+	}
+	if reportErr := b.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
+
+// CreateIssue creates an issue tagged with reqID, so the plugin can find
+// it again when the request is resolved.
+func (b *Bot) CreateIssue(ctx context.Context, reqID string, reqData requestData) (jiraData, error) {
+	issue, res, err := b.client.Issue.Create(&jira.Issue{
+		Properties: map[string]interface{}{
+			requestIDPropertyKey: reqID,
+		},
+		Fields: &jira.IssueFields{
+			Type:    jira.IssueType{Name: "Task"},
+			Project: jira.Project{Key: b.project},
+			Summary: fmt.Sprintf("%s is requesting roles %v", reqData.user, reqData.roles),
+		},
+	})
+	b.reportStatus(ctx, res, err)
+	if err != nil {
+		return jiraData{}, trace.Wrap(err)
+	}
+	return jiraData{ID: issue.ID, Key: issue.Key}, nil
+}
+
+// GetIssue fetches an issue by ID (or key), including its changelog and
+// the transitions currently available on it.
+func (b *Bot) GetIssue(ctx context.Context, issueID string) (*Issue, error) {
+	jiraIssue, res, err := b.client.Issue.Get(issueID, &jira.GetQueryOptions{
+		Expand:     "changelog,transitions",
+		Properties: requestIDPropertyKey,
+	})
+	b.reportStatus(ctx, res, err)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	issue := Issue(*jiraIssue)
+	return &issue, nil
+}
+
+// TransitionIssue moves issue to the given status (e.g. "approved",
+// "denied"), if JIRA's workflow currently allows that transition.
+func (b *Bot) TransitionIssue(ctx context.Context, issue *Issue, toStatus string) error {
+	transition, err := issue.GetTransition(toStatus)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	res, err := b.client.Issue.DoTransition(issue.ID, transition.ID)
+	b.reportStatus(ctx, res, err)
+	return trace.Wrap(err)
+}
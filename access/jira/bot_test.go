@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestJiraBot(t *testing.T) { TestingT(t) }
+
+type BotSuite struct{}
+
+var _ = Suite(&BotSuite{})
+
+func (s *BotSuite) TestIssueGetRequestID(c *C) {
+	issue := Issue{Properties: map[string]interface{}{requestIDPropertyKey: "req-42"}}
+	reqID, err := issue.GetRequestID()
+	c.Assert(err, IsNil)
+	c.Assert(reqID, Equals, "req-42")
+}
+
+func (s *BotSuite) TestIssueGetRequestIDMissing(c *C) {
+	issue := Issue{Properties: map[string]interface{}{}}
+	_, err := issue.GetRequestID()
+	c.Assert(err, NotNil)
+}
+
+func (s *BotSuite) TestIssueGetTransition(c *C) {
+	issue := Issue{Transitions: []jira.Transition{
+		{ID: "1", To: jira.Status{Name: "Approved"}},
+		{ID: "2", To: jira.Status{Name: "Denied"}},
+	}}
+
+	transition, err := issue.GetTransition("approved")
+	c.Assert(err, IsNil)
+	c.Assert(transition.ID, Equals, "1")
+}
+
+func (s *BotSuite) TestIssueGetTransitionMissing(c *C) {
+	issue := Issue{Transitions: []jira.Transition{
+		{ID: "1", To: jira.Status{Name: "Approved"}},
+	}}
+	_, err := issue.GetTransition("denied")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"github.com/gravitational/teleport-plugins/access/clientauth"
+	"github.com/gravitational/teleport-plugins/utils"
+	"github.com/gravitational/trace"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultDir is the default directory the JIRA plugin keeps generated
+// certificates (see utils.HTTP.EnsureCert) in.
+const DefaultDir = "/var/lib/teleport/plugins/jira"
+
+// Config is the JIRA access-request plugin's configuration, loaded from
+// the YAML file passed on the command line.
+type Config struct {
+	Teleport struct {
+		AuthServer string `yaml:"auth-server"`
+		ClientCrt  string `yaml:"client-crt"`
+		ClientKey  string `yaml:"client-key"`
+		RootCAs    string `yaml:"root-cas"`
+	} `yaml:"teleport"`
+	JIRA struct {
+		URL      string `yaml:"url"`
+		Username string `yaml:"username"`
+		APIToken string `yaml:"api-token"`
+		Project  string `yaml:"project"`
+	} `yaml:"jira"`
+	HTTP utils.HTTPConfig `yaml:"http"`
+
+	// ClientAuth optionally requires a verified client identity on every
+	// incoming webhook, since JIRA webhooks carry no signature of their
+	// own; see access/clientauth. Set HTTP.ClientCA to additionally
+	// require a verified mTLS client certificate at the listener level.
+	ClientAuth clientauth.Config `yaml:"client-auth"`
+}
+
+// LoadConfig reads and parses a Config from the YAML file at path.
+func LoadConfig(path string) (*Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var conf Config
+	if err := yaml.Unmarshal(bytes, &conf); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if conf.JIRA.Project == "" {
+		return nil, trace.BadParameter("jira.project must be set")
+	}
+	return &conf, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gravitational/trace"
+)
+
+// eprintln prints an optionally formatted string to stderr.
+func eprintln(msg string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, msg, a...)
+	fmt.Fprintf(os.Stderr, "\n")
+}
+
+func main() {
+	pgrm := os.Args[0]
+	args := os.Args[1:]
+	if len(args) < 1 {
+		eprintln("USAGE: %s (configure | <config-path>)", pgrm)
+		os.Exit(1)
+	}
+	if args[0] == "configure" {
+		fmt.Print(exampleConfig)
+		return
+	}
+	if err := run(args[0]); err != nil {
+		eprintln("ERROR: %s", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string) error {
+	conf, err := LoadConfig(configPath)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	app, err := NewApp(*conf)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return app.Run(context.Background())
+}
+
+const exampleConfig = `# JIRA access request plugin configuration.
+teleport:
+  auth-server: "example.com:3025"
+  client-crt: "/var/lib/teleport/plugins/jira/auth.crt"
+  client-key: "/var/lib/teleport/plugins/jira/auth.key"
+  root-cas: "/var/lib/teleport/plugins/jira/auth.cas"
+
+jira:
+  url: "https://your-org.atlassian.net"
+  username: "bot@example.com"
+  api-token: "your-jira-api-token"
+  project: "TELEPORT"
+
+http:
+  public-addr: "example.com:8081"
+  listen-addr: ":8081"
+  https-key-file: "/var/lib/teleport/plugins/jira/server.key"
+  https-cert-file: "/var/lib/teleport/plugins/jira/server.crt"
+`
@@ -10,6 +10,8 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/gravitational/teleport-plugins/access/clientauth"
+	"github.com/gravitational/teleport-plugins/access/status"
 	"github.com/gravitational/teleport-plugins/utils"
 	"github.com/gravitational/trace"
 	"github.com/julienschmidt/httprouter"
@@ -42,12 +44,13 @@ type WebhookFunc func(ctx context.Context, webhook Webhook) error
 // WebhookServer is a wrapper around http.Server that processes JIRA webhook events.
 // It verifies incoming requests and calls onWebhook for valid ones
 type WebhookServer struct {
-	http      *utils.HTTP
-	onWebhook WebhookFunc
-	counter   uint64
+	http       *utils.HTTP
+	onWebhook  WebhookFunc
+	counter    uint64
+	statusSink status.Sink
 }
 
-func NewWebhookServer(conf utils.HTTPConfig, onWebhook WebhookFunc) (*WebhookServer, error) {
+func NewWebhookServer(conf utils.HTTPConfig, authConf clientauth.Config, onWebhook WebhookFunc) (*WebhookServer, error) {
 	httpSrv, err := utils.NewHTTP(conf)
 	if err != nil {
 		return nil, trace.Wrap(err)
@@ -56,10 +59,23 @@ func NewWebhookServer(conf utils.HTTPConfig, onWebhook WebhookFunc) (*WebhookSer
 		http:      httpSrv,
 		onWebhook: onWebhook,
 	}
-	httpSrv.POST("/", srv.processWebhook)
+	handler, err := clientauth.Middleware(authConf, srv.processWebhook)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	httpSrv.POST("/", handler)
 	return srv, nil
 }
 
+// SetStatusSink wires a status.Sink that the server will report to
+// whenever it fails to process a webhook, deduplicated so a sustained
+// failure doesn't spam the sink with identical reports. App.Run wires this
+// up alongside Bot.SetStatusSink, the same way access/slack's and
+// access/gitlab's Apps do.
+func (s *WebhookServer) SetStatusSink(sink status.Sink) {
+	s.statusSink = status.NewDedup(sink)
+}
+
 func (s *WebhookServer) ServiceJob() utils.ServiceJob {
 	return s.http.ServiceJob()
 }
@@ -103,8 +119,25 @@ func (s *WebhookServer) processWebhook(rw http.ResponseWriter, r *http.Request,
 		default:
 			code = http.StatusInternalServerError
 		}
+		s.reportStatus(ctx, status.FromHTTPStatusCode(code), err)
 		http.Error(rw, "", code)
 	} else {
+		s.reportStatus(ctx, status.Running, nil)
 		rw.WriteHeader(http.StatusOK)
 	}
 }
+
+// reportStatus pushes a plugin health update to the configured status
+// sink, if any. It's a no-op when no sink has been set.
+func (s *WebhookServer) reportStatus(ctx context.Context, code status.Code, err error) {
+	if s.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{Code: code, LastReported: time.Now()}
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	if reportErr := s.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
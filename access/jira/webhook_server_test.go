@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gravitational/teleport-plugins/access/clientauth"
+	"github.com/gravitational/teleport-plugins/utils"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestJiraWebhookServer(t *testing.T) { TestingT(t) }
+
+type WebhookServerSuite struct{}
+
+var _ = Suite(&WebhookServerSuite{})
+
+func (s *WebhookServerSuite) newServer(c *C) *WebhookServer {
+	srv, err := NewWebhookServer(
+		utils.HTTPConfig{ListenAddr: "127.0.0.1:0", Insecure: true},
+		clientauth.Config{},
+		func(ctx context.Context, webhook Webhook) error { return nil },
+	)
+	c.Assert(err, IsNil)
+	return srv
+}
+
+func (s *WebhookServerSuite) TestRejectsMissingTrustedHeader(c *C) {
+	srv := s.newServer(c)
+	authConf := clientauth.Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `^CN=teleport-plugin\.example\.com$`,
+	}
+	handler, err := clientauth.Middleware(authConf, srv.processWebhook)
+	c.Assert(err, IsNil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+	handler(rec, req, nil)
+	c.Assert(rec.Code, Equals, 401)
+}
+
+func (s *WebhookServerSuite) TestRejectsWrongTrustedHeader(c *C) {
+	srv := s.newServer(c)
+	authConf := clientauth.Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `^CN=teleport-plugin\.example\.com$`,
+	}
+	handler, err := clientauth.Middleware(authConf, srv.processWebhook)
+	c.Assert(err, IsNil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("X-Forwarded-Client-Cert-DN", "CN=someone-else")
+	handler(rec, req, nil)
+	c.Assert(rec.Code, Equals, 401)
+}
+
+func (s *WebhookServerSuite) TestAllowsValidTrustedHeader(c *C) {
+	srv := s.newServer(c)
+	authConf := clientauth.Config{
+		TrustedDNHeader:  "X-Forwarded-Client-Cert-DN",
+		TrustedDNPattern: `^CN=teleport-plugin\.example\.com$`,
+	}
+	handler, err := clientauth.Middleware(authConf, srv.processWebhook)
+	c.Assert(err, IsNil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("X-Forwarded-Client-Cert-DN", "CN=teleport-plugin.example.com")
+	handler(rec, req, nil)
+	c.Assert(rec.Code, Equals, 200)
+}
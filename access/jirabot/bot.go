@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	jira "gopkg.in/andygrunwald/go-jira.v1"
 
+	"github.com/gravitational/teleport-plugins/access/status"
 	"github.com/gravitational/trace"
 	log "github.com/sirupsen/logrus"
 )
@@ -25,6 +27,7 @@ type Bot struct {
 	client      *jira.Client
 	project     string
 	clusterName string
+	statusSink  status.Sink
 }
 
 type Issue jira.Issue
@@ -100,6 +103,45 @@ func NewBot(conf *Config) (*Bot, error) {
 	}, nil
 }
 
+// SetStatusSink wires a status.Sink that the bot will report its health to
+// on every call to the JIRA API, deduplicated so a sustained outage doesn't
+// spam the sink with identical reports.
+//
+// NB: this standalone jirabot package predates access/jira and access/slack's
+// App-based design and has no App of its own to call SetStatusSink or Ping,
+// so it's currently unwired dead code (it also has other broken references,
+// e.g. the undefined requestData and jiraData types above). access/jira now
+// has a working App with its own Bot, WebhookServer, status sink, and Ping -
+// this package is superseded by it and kept only for reference.
+func (c *Bot) SetStatusSink(sink status.Sink) {
+	c.statusSink = status.NewDedup(sink)
+}
+
+// reportStatus classifies err (and, if available, the JIRA response that
+// produced it) and pushes the result to the bot's status sink. It's a
+// no-op when no sink is configured, so callers can use it unconditionally.
+func (c *Bot) reportStatus(ctx context.Context, res *jira.Response, err error) {
+	if c.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{LastReported: time.Now()}
+	if err == nil {
+		st.Code = status.Running
+	} else {
+		st.LastError = err.Error()
+		if res != nil && (res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden) {
+			st.Code = status.Unauthorized
+		} else if res != nil {
+			st.Code = status.FromHTTPStatusCode(res.StatusCode)
+		} else {
+			st.Code = status.OtherError
+		}
+	}
+	if reportErr := c.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
+
 // CreateIssue creates an issue with "Pending" status
 func (c *Bot) CreateIssue(reqID string, reqData requestData) (data jiraData, err error) {
 	issue, res, err := c.client.Issue.Create(&jira.Issue{
@@ -112,6 +154,7 @@ func (c *Bot) CreateIssue(reqID string, reqData requestData) (data jiraData, err
 			Summary: fmt.Sprintf("Incoming request %s", reqID),
 		},
 	})
+	c.reportStatus(context.Background(), res, err)
 	if err != nil {
 		body, err := parseErrorResponse(res, err)
 		log.Error(body)
@@ -128,6 +171,7 @@ func (c *Bot) GetIssue(issueID string) (*Issue, error) {
 		Expand:     "changelog,transitions",
 		Properties: RequestIdPropertyKey,
 	})
+	c.reportStatus(context.Background(), res, err)
 	if err != nil {
 		err = trace.Wrap(err)
 		body, err := parseErrorResponse(res, trace.Wrap(err))
@@ -152,6 +196,7 @@ func (c *Bot) ExpireIssue(reqID string, reqData requestData, jiraData jiraData)
 	}
 
 	res, err := c.client.Issue.DoTransition(issue.ID, transition.ID)
+	c.reportStatus(context.Background(), res, err)
 	if err != nil {
 		body, err := parseErrorResponse(res, err)
 		log.Error(body)
@@ -0,0 +1,49 @@
+package notify
+
+import "github.com/gravitational/trace"
+
+// Config is one entry of an App's `notifiers:` YAML list: a discriminated
+// union selecting which backend to build and its settings.
+type Config struct {
+	// Type selects the notifier backend: "webhook", "teams" or "email".
+	Type    string         `yaml:"type"`
+	Webhook *WebhookConfig `yaml:"webhook,omitempty"`
+	Teams   *TeamsConfig   `yaml:"teams,omitempty"`
+	Email   *EmailConfig   `yaml:"email,omitempty"`
+}
+
+// Build constructs the Notifier that conf selects.
+func Build(conf Config) (Notifier, error) {
+	switch conf.Type {
+	case "webhook":
+		if conf.Webhook == nil {
+			return nil, trace.BadParameter(`notifiers: type "webhook" requires a webhook: section`)
+		}
+		return NewWebhookNotifier(*conf.Webhook), nil
+	case "teams":
+		if conf.Teams == nil {
+			return nil, trace.BadParameter(`notifiers: type "teams" requires a teams: section`)
+		}
+		return NewTeamsNotifier(*conf.Teams), nil
+	case "email":
+		if conf.Email == nil {
+			return nil, trace.BadParameter(`notifiers: type "email" requires an email: section`)
+		}
+		return NewEmailNotifier(*conf.Email), nil
+	default:
+		return nil, trace.BadParameter("notifiers: unknown type %q", conf.Type)
+	}
+}
+
+// BuildAll builds every Notifier in confs.
+func BuildAll(confs []Config) ([]Notifier, error) {
+	notifiers := make([]Notifier, 0, len(confs))
+	for _, conf := range confs {
+		notifier, err := Build(conf)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	return notifiers, nil
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// dialTimeout bounds how long EmailNotifier will wait to connect to and
+// converse with the SMTP server, the same way WebhookNotifier bounds its
+// HTTP client: smtp.SendMail has no built-in deadline, so without one a
+// wedged or unreachable server would block fanOut's goroutine (and
+// whatever's waiting on its result) forever.
+const dialTimeout = 10 * time.Second
+
+// EmailConfig configures an SMTP email notifier.
+type EmailConfig struct {
+	// SMTPServer is the "host:port" of the SMTP server to relay through.
+	SMTPServer string `yaml:"smtp-server"`
+	// Username/Password are PLAIN AUTH credentials. Leave both empty to
+	// skip authentication.
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// EmailNotifier is a Notifier that sends a plain-text email per lifecycle
+// event. Like WebhookNotifier and TeamsNotifier, it keeps no per-request
+// state: there's no inbox message to go back and edit.
+type EmailNotifier struct {
+	conf EmailConfig
+}
+
+// NewEmailNotifier builds an EmailNotifier from conf.
+func NewEmailNotifier(conf EmailConfig) *EmailNotifier {
+	return &EmailNotifier{conf: conf}
+}
+
+func (e *EmailNotifier) Name() string { return "email" }
+
+func (e *EmailNotifier) OnPending(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	subject := fmt.Sprintf("[access-request] %s is requesting roles %v", req.User, req.Roles)
+	body := fmt.Sprintf("User: %s\nRoles: %v\nCluster: %s\nTTL: %s\nCreated: %s\n",
+		req.User, req.Roles, req.Cluster, req.TTL, req.Created)
+	return data, e.send(ctx, subject, body)
+}
+
+func (e *EmailNotifier) OnResolved(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	subject := fmt.Sprintf("[access-request] %s was %s", req.ID, req.State)
+	body := fmt.Sprintf("Request: %s\nUser: %s\nState: %s\nReviewer: %s\nReason: %s\n",
+		req.ID, req.User, req.State, req.Reviewer, req.Reason)
+	return data, e.send(ctx, subject, body)
+}
+
+func (e *EmailNotifier) OnExpired(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	subject := fmt.Sprintf("[access-request] %s expired", req.ID)
+	body := fmt.Sprintf("Request: %s\nUser: %s\n", req.ID, req.User)
+	return data, e.send(ctx, subject, body)
+}
+
+// send dials e.conf.SMTPServer and relays subject/body, bounding every step
+// (dial, auth, DATA) with dialTimeout or ctx's own deadline, whichever is
+// sooner, so a wedged server can't block the caller indefinitely the way a
+// bare smtp.SendMail call would.
+func (e *EmailNotifier) send(ctx context.Context, subject, body string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.conf.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.conf.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprint(&msg, "\r\n")
+	fmt.Fprint(&msg, body)
+
+	host, _, err := net.SplitHostPort(e.conf.SMTPServer)
+	if err != nil {
+		return trace.Wrap(err, "invalid smtp-server %q", e.conf.SMTPServer)
+	}
+
+	deadline := time.Now().Add(dialTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	var d net.Dialer
+	dialCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	conn, err := d.DialContext(dialCtx, "tcp", e.conf.SMTPServer)
+	if err != nil {
+		return trace.Wrap(err, "dialing smtp-server %q", e.conf.SMTPServer)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return trace.Wrap(err)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return trace.Wrap(err)
+	}
+	defer client.Close()
+
+	if e.conf.Username != "" {
+		auth := smtp.PlainAuth("", e.conf.Username, e.conf.Password, host)
+		if err := client.Auth(auth); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	if err := client.Mail(e.conf.From); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, to := range e.conf.To {
+		if err := client.Rcpt(to); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return trace.Wrap(err)
+	}
+	if err := w.Close(); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(client.Quit())
+}
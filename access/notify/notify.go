@@ -0,0 +1,92 @@
+// Package notify defines the Notifier interface that every access-request
+// notification backend (Slack, MS Teams, a generic outbound webhook, SMTP
+// email, ...) implements, so an App can fan a single pending request out
+// to several of them at once without knowing which ones are configured.
+package notify
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// RequestData is the subset of an access.Request (plus, once resolved, who
+// resolved it and why) that a Notifier needs in order to render a
+// notification. It's deliberately independent of the access package so
+// notifiers don't need to import Teleport's client library.
+type RequestData struct {
+	ID      string
+	User    string
+	Roles   []string
+	Cluster string
+	Created time.Time
+	TTL     time.Duration
+
+	// State is "pending", "approved", "denied" or "expired".
+	State string
+	// Reviewer identifies who resolved the request, e.g. "slack:bob@example.com".
+	// Empty for OnPending and OnExpired.
+	Reviewer string
+	// Reason is the reviewer-supplied justification for their decision, if any.
+	Reason string
+}
+
+// NotifyData is per-notifier, per-request state a Notifier returns from
+// OnPending so it can find and update its own notification later (e.g. a
+// Slack message's channel/timestamp). It's a plain string map so it can be
+// persisted the same way as any other plugin data.
+type NotifyData map[string]string
+
+// Notifier is a single notification backend. An App calls OnPending once
+// per backend when a request first appears, then OnResolved or OnExpired
+// once the request leaves the pending state, threading back whatever
+// NotifyData the previous call returned.
+type Notifier interface {
+	// Name identifies this notifier. It's used to namespace this
+	// notifier's NotifyData among others' in a request's plugin data (see
+	// EncodeKeyed/DecodeKeyed), so it must be unique within an App's
+	// configured notifiers.
+	Name() string
+	OnPending(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error)
+	OnResolved(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error)
+	OnExpired(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error)
+}
+
+// EncodeKeyed namespaces data's keys under name, so that several
+// notifiers' NotifyData can be merged into one request's plugin data
+// without their keys colliding.
+func EncodeKeyed(name string, data NotifyData) map[string]string {
+	out := make(map[string]string, len(data))
+	for k, v := range data {
+		out[name+":"+k] = v
+	}
+	return out
+}
+
+// DecodeKeyed extracts the NotifyData that EncodeKeyed namespaced under
+// name back out of raw, a request's full plugin data.
+func DecodeKeyed(name string, raw map[string]string) NotifyData {
+	prefix := name + ":"
+	data := make(NotifyData)
+	for k, v := range raw {
+		if trimmed := strings.TrimPrefix(k, prefix); trimmed != k {
+			data[trimmed] = v
+		}
+	}
+	return data
+}
+
+// DecodeKeyedWithLegacyFallback behaves like DecodeKeyed, but if no key in
+// raw carries name's prefix, it falls back to returning raw unchanged.
+// It's for the one notifier (if any) that predates this package's
+// namespacing scheme, so requests already pending across an upgrade can
+// still be found under their old, unprefixed keys.
+func DecodeKeyedWithLegacyFallback(name string, raw map[string]string) NotifyData {
+	if data := DecodeKeyed(name, raw); len(data) > 0 {
+		return data
+	}
+	if len(raw) == 0 {
+		return NotifyData{}
+	}
+	return NotifyData(raw)
+}
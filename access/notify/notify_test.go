@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestNotify(t *testing.T) { TestingT(t) }
+
+type NotifySuite struct{}
+
+var _ = Suite(&NotifySuite{})
+
+func (s *NotifySuite) TestKeyedRoundTrip(c *C) {
+	slackData := NotifyData{"channel_id": "C123", "timestamp": "111.222"}
+	teamsData := NotifyData{"foo": "bar"}
+
+	merged := make(map[string]string)
+	for k, v := range EncodeKeyed("slack", slackData) {
+		merged[k] = v
+	}
+	for k, v := range EncodeKeyed("teams", teamsData) {
+		merged[k] = v
+	}
+
+	c.Assert(DecodeKeyed("slack", merged), DeepEquals, slackData)
+	c.Assert(DecodeKeyed("teams", merged), DeepEquals, teamsData)
+}
+
+func (s *NotifySuite) TestDecodeKeyedWithLegacyFallback(c *C) {
+	legacy := map[string]string{"channel_id": "C123", "timestamp": "111.222"}
+	c.Assert(DecodeKeyedWithLegacyFallback("slack", legacy), DeepEquals, NotifyData(legacy))
+
+	namespaced := EncodeKeyed("slack", NotifyData{"channel_id": "C123"})
+	c.Assert(DecodeKeyedWithLegacyFallback("slack", namespaced), DeepEquals, NotifyData{"channel_id": "C123"})
+
+	c.Assert(DecodeKeyedWithLegacyFallback("slack", nil), DeepEquals, NotifyData{})
+}
+
+func (s *NotifySuite) TestWebhookSignsPayload(c *C) {
+	const secret = "s3cr3t"
+
+	var gotBody []byte
+	var gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotBody, _ = ioutil.ReadAll(r.Body)
+		gotSignature = r.Header.Get("X-Notify-Signature")
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhookNotifier(WebhookConfig{URL: srv.URL, Secret: secret})
+	_, err := notifier.OnPending(context.Background(), RequestData{ID: "req-1", User: "alice"}, nil)
+	c.Assert(err, IsNil)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(gotBody)
+	c.Assert(gotSignature, Equals, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	var payload webhookPayload
+	c.Assert(json.Unmarshal(gotBody, &payload), IsNil)
+	c.Assert(payload.Event, Equals, "pending")
+	c.Assert(payload.Request.ID, Equals, "req-1")
+}
+
+func (s *NotifySuite) TestWebhookReportsNon2xx(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhookNotifier(WebhookConfig{URL: srv.URL})
+	_, err := notifier.OnResolved(context.Background(), RequestData{ID: "req-1"}, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *NotifySuite) TestTeamsPostsAdaptiveCard(c *C) {
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		gotBody, _ = ioutil.ReadAll(r.Body)
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	notifier := NewTeamsNotifier(TeamsConfig{WebhookURL: srv.URL})
+	_, err := notifier.OnPending(context.Background(), RequestData{User: "alice", Roles: []string{"admin"}}, nil)
+	c.Assert(err, IsNil)
+
+	var envelope adaptiveCardEnvelope
+	c.Assert(json.Unmarshal(gotBody, &envelope), IsNil)
+	c.Assert(envelope.Attachments, HasLen, 1)
+	c.Assert(envelope.Attachments[0].ContentType, Equals, "application/vnd.microsoft.card.adaptive")
+	c.Assert(strings.Contains(string(gotBody), "alice"), Equals, true)
+}
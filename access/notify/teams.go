@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// TeamsConfig configures a Microsoft Teams notifier: an incoming webhook
+// connector URL that adaptive-card payloads are POSTed to.
+type TeamsConfig struct {
+	// WebhookURL is the incoming webhook URL a Teams channel connector
+	// generates.
+	WebhookURL string `yaml:"webhook-url"`
+}
+
+// TeamsNotifier is a Notifier that posts an adaptive card to a Teams
+// channel via its incoming webhook connector. Like WebhookNotifier, it
+// keeps no per-request state: Teams incoming webhooks can't be edited
+// after the fact, so every lifecycle event is posted as a new card.
+type TeamsNotifier struct {
+	conf   TeamsConfig
+	client *http.Client
+}
+
+// NewTeamsNotifier builds a TeamsNotifier from conf.
+func NewTeamsNotifier(conf TeamsConfig) *TeamsNotifier {
+	return &TeamsNotifier{conf: conf, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (t *TeamsNotifier) Name() string { return "teams" }
+
+func (t *TeamsNotifier) OnPending(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	title := fmt.Sprintf("%s is requesting roles %v", req.User, req.Roles)
+	return data, t.post(ctx, title, req)
+}
+
+func (t *TeamsNotifier) OnResolved(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	title := fmt.Sprintf("Request %s %s by %s", req.ID, req.State, req.Reviewer)
+	return data, t.post(ctx, title, req)
+}
+
+func (t *TeamsNotifier) OnExpired(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	title := fmt.Sprintf("Request %s expired", req.ID)
+	return data, t.post(ctx, title, req)
+}
+
+// adaptiveCardEnvelope is the message format Teams incoming webhooks
+// expect when the content is an adaptive card rather than the legacy
+// MessageCard schema.
+type adaptiveCardEnvelope struct {
+	Type        string       `json:"type"`
+	Attachments []attachment `json:"attachments"`
+}
+
+type attachment struct {
+	ContentType string `json:"contentType"`
+	Content     card   `json:"content"`
+}
+
+type card struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []interface{} `json:"body"`
+}
+
+type textBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+}
+
+type fact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type factSet struct {
+	Type  string `json:"type"`
+	Facts []fact `json:"facts"`
+}
+
+func (t *TeamsNotifier) post(ctx context.Context, title string, req RequestData) error {
+	envelope := adaptiveCardEnvelope{
+		Type: "message",
+		Attachments: []attachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: card{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []interface{}{
+					textBlock{Type: "TextBlock", Text: title, Weight: "bolder", Size: "medium"},
+					factSet{Type: "FactSet", Facts: []fact{
+						{Title: "Requester", Value: req.User},
+						{Title: "Roles", Value: fmt.Sprintf("%v", req.Roles)},
+						{Title: "Cluster", Value: req.Cluster},
+						{Title: "TTL", Value: req.TTL.String()},
+					}},
+				},
+			},
+		}},
+	}
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.conf.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return trace.Errorf("teams notifier: %s returned %s", t.conf.WebhookURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gravitational/trace"
+)
+
+// WebhookConfig configures a generic outbound webhook notifier: on every
+// request lifecycle event, it POSTs a JSON payload to URL, signing it with
+// Secret the same way Slack/GitHub-style webhook senders do, so the
+// receiver can verify the payload actually came from us.
+type WebhookConfig struct {
+	// URL is where the JSON payload is POSTed.
+	URL string `yaml:"url"`
+	// Secret, if set, signs every payload with HMAC-SHA256; the signature
+	// is sent as the X-Notify-Signature header, hex-encoded and prefixed
+	// "sha256=".
+	Secret string `yaml:"secret"`
+}
+
+// WebhookNotifier is a Notifier that POSTs a JSON event to a configured
+// URL. It keeps no per-request NotifyData of its own: the receiving end is
+// expected to be a one-way consumer (a chat-ops bridge, an incident
+// pipeline, ...), not something this plugin can later edit a message on.
+type WebhookNotifier struct {
+	conf   WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookNotifier builds a WebhookNotifier from conf.
+func NewWebhookNotifier(conf WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{conf: conf, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *WebhookNotifier) Name() string { return "webhook" }
+
+func (w *WebhookNotifier) OnPending(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	return data, w.post(ctx, "pending", req)
+}
+
+func (w *WebhookNotifier) OnResolved(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	return data, w.post(ctx, "resolved", req)
+}
+
+func (w *WebhookNotifier) OnExpired(ctx context.Context, req RequestData, data NotifyData) (NotifyData, error) {
+	return data, w.post(ctx, "expired", req)
+}
+
+// webhookPayload is the JSON body POSTed to WebhookConfig.URL.
+type webhookPayload struct {
+	Event   string      `json:"event"`
+	Request RequestData `json:"request"`
+}
+
+func (w *WebhookNotifier) post(ctx context.Context, event string, req RequestData) error {
+	body, err := json.Marshal(webhookPayload{Event: event, Request: req})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if w.conf.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.conf.Secret))
+		mac.Write(body)
+		httpReq.Header.Set("X-Notify-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(httpReq)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return trace.Errorf("webhook notifier: %s returned %s", w.conf.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/gravitational/teleport-plugins/access"
+	"github.com/gravitational/teleport-plugins/access/notify"
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/trace"
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// pingInterval is how often Run polls Slack's health via Bot.Ping, so the
+// plugin's reported status reflects reality even while no access request
+// is pending to otherwise exercise the API.
+const pingInterval = 5 * time.Minute
+
+// App is the Slack access-request plugin process: it watches Teleport for
+// pending access requests, fans each one out to Slack (and any other
+// configured notify.Notifier), and listens for Slack's approve/deny
+// button clicks to drive them to a final state.
+type App struct {
+	conf Config
+
+	client       access.Client
+	bot          *Bot
+	notifiers    []notify.Notifier
+	webhookSrv   *WebhookServer
+	socketClient *SocketClient
+	statusSink   status.Sink
+
+	readyCh chan struct{}
+	doneCh  chan struct{}
+	runErr  error
+}
+
+// NewApp builds an App from conf, but does not start it; call Run to do
+// that.
+func NewApp(conf Config) (*App, error) {
+	bot := NewBot(&conf)
+
+	extraNotifiers, err := notify.BuildAll(conf.Notifiers)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	notifiers := append([]notify.Notifier{&botNotifier{bot: bot}}, extraNotifiers...)
+
+	app := &App{
+		conf:      conf,
+		bot:       bot,
+		notifiers: notifiers,
+		readyCh:   make(chan struct{}),
+		doneCh:    make(chan struct{}),
+	}
+
+	if conf.Slack.Mode == "socket" {
+		app.socketClient = NewSocketClient(conf.Slack.AppToken, conf.Slack.APIURL, app.onBlockAction)
+		return app, nil
+	}
+
+	webhookSrv, err := NewWebhookServer(conf.HTTP, conf.ClientAuth, conf.Slack.Secret, conf.Slack.NotifyOnly, app.onBlockAction)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	app.webhookSrv = webhookSrv
+
+	return app, nil
+}
+
+// PublicURL is the URL operators must make reachable by Slack in http
+// transport mode. It's nil in socket mode, which needs no inbound URL at
+// all (see socket.go).
+func (a *App) PublicURL() *url.URL {
+	if a.webhookSrv == nil {
+		return nil
+	}
+	return a.webhookSrv.BaseURL()
+}
+
+// WaitReady blocks until the App has finished starting up (or ctx is
+// done), returning whether it's actually ready.
+func (a *App) WaitReady(ctx context.Context) (bool, error) {
+	select {
+	case <-a.readyCh:
+		return true, nil
+	case <-a.doneCh:
+		return false, a.runErr
+	case <-ctx.Done():
+		return false, trace.Wrap(ctx.Err())
+	}
+}
+
+// Shutdown stops the App and waits for Run to return.
+func (a *App) Shutdown(ctx context.Context) error {
+	select {
+	case <-a.doneCh:
+		return a.runErr
+	case <-ctx.Done():
+		return trace.Wrap(ctx.Err())
+	}
+}
+
+// Run starts the App: it connects to Teleport, wires up the default
+// status sink (when the client supports it), starts the webhook server,
+// and watches for pending access requests until ctx is canceled.
+func (a *App) Run(ctx context.Context) error {
+	defer close(a.doneCh)
+
+	tc, err := a.loadTLSConfig()
+	if err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+
+	client, err := access.NewClient(ctx, a.conf.Teleport.AuthServer, tc)
+	if err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+	a.client = client
+
+	a.statusSink = status.NewDefaultSink("slack", client)
+	a.bot.SetStatusSink(a.statusSink)
+	if a.socketClient != nil {
+		a.socketClient.SetStatusSink(a.statusSink)
+	} else {
+		a.webhookSrv.SetStatusSink(a.statusSink)
+	}
+
+	watcher, err := client.WatchRequests(ctx, access.Filter{State: access.StatePending})
+	if err != nil {
+		a.runErr = trace.Wrap(err)
+		return a.runErr
+	}
+	defer watcher.Close()
+
+	transportErrCh := make(chan error, 1)
+	if a.socketClient != nil {
+		go func() { transportErrCh <- a.socketClient.Run(ctx) }()
+	} else {
+		if err := a.webhookSrv.EnsureCert(); err != nil {
+			a.runErr = trace.Wrap(err)
+			return a.runErr
+		}
+		httpJob := a.webhookSrv.ServiceJob()
+		go func() { transportErrCh <- httpJob(ctx) }()
+	}
+
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	close(a.readyCh)
+
+	for {
+		select {
+		case event := <-watcher.Events():
+			if err := a.handleEvent(ctx, event); err != nil {
+				a.runErr = trace.Wrap(err)
+				return a.runErr
+			}
+		case err := <-transportErrCh:
+			a.runErr = trace.Wrap(err)
+			return a.runErr
+		case <-watcher.Done():
+			a.runErr = trace.Wrap(watcher.Error())
+			return a.runErr
+		case <-pingTicker.C:
+			if err := a.Ping(ctx); err != nil {
+				log.WithError(err).Error("Failed to ping Slack")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Ping performs a lightweight round-trip to Slack and reports the result
+// to the App's status sink, so a Slack outage is reflected in plugin
+// status even while no access request is pending to otherwise exercise
+// the API.
+func (a *App) Ping(ctx context.Context) error {
+	return trace.Wrap(a.bot.Ping(ctx))
+}
+
+func (a *App) loadTLSConfig() (*access.TLSConfig, error) {
+	return access.LoadTLSConfig(a.conf.Teleport.ClientCrt, a.conf.Teleport.ClientKey, a.conf.Teleport.RootCAs)
+}
+
+func (a *App) handleEvent(ctx context.Context, event access.Event) error {
+	req, op := event.Request, event.Type
+	switch op {
+	case access.OpInit:
+		return nil
+	case access.OpPut:
+		return a.handlePendingRequest(ctx, req)
+	case access.OpDelete:
+		return nil
+	default:
+		return trace.BadParameter("unexpected event operation %s", op)
+	}
+}
+
+// handlePendingRequest fans req out to every configured notifier. A
+// notifier failing (e.g. an unreachable Teams webhook) doesn't stop the
+// others, and doesn't stop the ones that did succeed (Slack's message,
+// say) from being persisted: only a failure to reach Teleport itself is
+// fatal to the plugin process.
+func (a *App) handlePendingRequest(ctx context.Context, req access.Request) error {
+	pluginData, fanOutErr := a.fanOut(ctx, toNotifyRequestData(req, "pending", "", ""), nil,
+		func(n notify.Notifier, ctx context.Context, nreq notify.RequestData, data notify.NotifyData) (notify.NotifyData, error) {
+			return n.OnPending(ctx, nreq, data)
+		})
+	if err := a.client.UpdatePluginData(ctx, req.ID, pluginData); err != nil {
+		return trace.Wrap(err)
+	}
+	if fanOutErr != nil {
+		log.WithError(fanOutErr).Error("One or more notifiers failed to report a pending request")
+	}
+	return nil
+}
+
+func toNotifyRequestData(req access.Request, state, reviewer, reason string) notify.RequestData {
+	return notify.RequestData{
+		ID:       req.ID,
+		User:     req.User,
+		Roles:    req.Roles,
+		Cluster:  req.ClusterName,
+		Created:  req.Created,
+		TTL:      req.Expires.Sub(req.Created),
+		State:    state,
+		Reviewer: reviewer,
+		Reason:   reason,
+	}
+}
+
+// notifierCall is OnPending, OnResolved or OnExpired: whichever lifecycle
+// method fanOut should invoke on every configured notifier.
+type notifierCall func(n notify.Notifier, ctx context.Context, req notify.RequestData, data notify.NotifyData) (notify.NotifyData, error)
+
+// fanOutResult is one notifier's outcome, collected back onto fanOut's
+// calling goroutine over a channel.
+type fanOutResult struct {
+	name string
+	// data is what the notifier's NotifyData should be persisted as: its
+	// own return value on success, or its unchanged prior data on error,
+	// so a transient failure doesn't erase earlier progress.
+	data notify.NotifyData
+	err  error
+}
+
+// fanOut invokes call concurrently on every configured notifier, passing
+// each its own previously-persisted NotifyData (extracted from rawData by
+// name), and merges what they return back into a single plugin-data map
+// keyed by notifier name so none of their state collides.
+func (a *App) fanOut(ctx context.Context, req notify.RequestData, rawData map[string]string, call notifierCall) (map[string]string, error) {
+	results := make(chan fanOutResult, len(a.notifiers))
+	for _, n := range a.notifiers {
+		n := n
+		// The Slack notifier predates this package's key-namespacing
+		// scheme, so a request left pending across an upgrade may still
+		// carry its data under the old, unprefixed keys.
+		var priorData notify.NotifyData
+		if n.Name() == "slack" {
+			priorData = notify.DecodeKeyedWithLegacyFallback(n.Name(), rawData)
+		} else {
+			priorData = notify.DecodeKeyed(n.Name(), rawData)
+		}
+		go func() {
+			newData, err := call(n, ctx, req, priorData)
+			if err != nil {
+				results <- fanOutResult{name: n.Name(), data: priorData, err: err}
+				return
+			}
+			results <- fanOutResult{name: n.Name(), data: newData}
+		}()
+	}
+
+	merged := make(map[string]string)
+	var errs []error
+	for i := 0; i < len(a.notifiers); i++ {
+		select {
+		case res := <-results:
+			if res.err != nil {
+				errs = append(errs, trace.Wrap(res.err, "notifier %q failed", res.name))
+			}
+			for k, v := range notify.EncodeKeyed(res.name, res.data) {
+				merged[k] = v
+			}
+		case <-ctx.Done():
+			// Don't let a wedged notifier block the event loop forever:
+			// give up on the stragglers and report what we already have.
+			// Their goroutines are left to finish (or fail) on their own;
+			// any NotifyData they'd have returned is simply not persisted
+			// this round.
+			errs = append(errs, trace.Wrap(ctx.Err(), "gave up waiting on %d notifier(s)", len(a.notifiers)-i))
+			return merged, trace.NewAggregate(errs...)
+		}
+	}
+	if len(errs) > 0 {
+		return merged, trace.NewAggregate(errs...)
+	}
+	return merged, nil
+}
+
+// onBlockAction resolves the Slack user who clicked a button to their
+// email, drives the access request to its new state (recording the Slack
+// identity as the delegator in Teleport's audit log), then fans the
+// resolution out to every configured notifier so each can update (or send)
+// its own notification.
+func (a *App) onBlockAction(ctx context.Context, action *slack.BlockAction, userID string) error {
+	var state access.State
+	var stateName string
+	switch action.ActionID {
+	case "approve_request":
+		state, stateName = access.StateApproved, "approved"
+	case "deny_request":
+		state, stateName = access.StateDenied, "denied"
+	default:
+		return trace.BadParameter("unknown action %q", action.ActionID)
+	}
+
+	reqID := action.Value
+
+	user, err := a.bot.client.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	reviewer := "slack:" + user.Profile.Email
+
+	if err := a.client.SetRequestState(ctx, reqID, state, reviewer); err != nil {
+		return trace.Wrap(err)
+	}
+
+	req, err := a.client.GetRequest(ctx, reqID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	rawData, err := a.client.GetPluginData(ctx, reqID)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	// The request's state in Teleport is already final at this point; a
+	// notifier failing to relay that (a Teams webhook down, say) shouldn't
+	// be treated as this callback failing.
+	pluginData, fanOutErr := a.fanOut(ctx, toNotifyRequestData(req, stateName, reviewer, ""), rawData,
+		func(n notify.Notifier, ctx context.Context, nreq notify.RequestData, data notify.NotifyData) (notify.NotifyData, error) {
+			return n.OnResolved(ctx, nreq, data)
+		})
+	if err := a.client.UpdatePluginData(ctx, reqID, pluginData); err != nil {
+		return trace.Wrap(err)
+	}
+	if fanOutErr != nil {
+		log.WithError(fanOutErr).Error("One or more notifiers failed to report a resolved request")
+	}
+	return nil
+}
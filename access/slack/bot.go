@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/gravitational/teleport-plugins/access"
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/trace"
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultMessageTemplate is used to render a pending request's headline
+// when Config.Slack.MessageTemplate isn't set.
+const defaultMessageTemplate = "{{.User}} is requesting roles {{.Roles}} on {{.Cluster}}"
+
+// messageTemplateData is what a Slack.MessageTemplate is executed
+// against.
+type messageTemplateData struct {
+	User    string
+	Roles   []string
+	Cluster string
+	TTL     time.Duration
+	Created time.Time
+}
+
+// attachmentColor mirrors the color convention common Slack
+// logrus/notification hooks use: green for a resolved-ok state, red for a
+// resolved-bad one, yellow while still undecided.
+func attachmentColor(state access.State) string {
+	switch state {
+	case access.StateApproved:
+		return "good"
+	case access.StateDenied:
+		return "danger"
+	default:
+		return "warning"
+	}
+}
+
+// PluginData is what the Slack plugin stashes in Teleport's plugin-data
+// store for a request, so it can find and update the message it posted
+// when the request's state changes.
+type PluginData struct {
+	ChannelID string
+	Timestamp string
+}
+
+// EncodePluginData turns a PluginData into the plain string map Teleport's
+// plugin-data API stores.
+func EncodePluginData(data PluginData) map[string]string {
+	return map[string]string{
+		"channel_id": data.ChannelID,
+		"timestamp":  data.Timestamp,
+	}
+}
+
+// DecodePluginData turns a plain string map from Teleport's plugin-data
+// API back into a PluginData.
+func DecodePluginData(raw map[string]string) PluginData {
+	return PluginData{
+		ChannelID: raw["channel_id"],
+		Timestamp: raw["timestamp"],
+	}
+}
+
+// requestData is the subset of an access.AccessRequest that the Slack bot
+// needs in order to render a message for it.
+type requestData struct {
+	user    string
+	roles   []string
+	cluster string
+	ttl     time.Duration
+	created time.Time
+}
+
+// Bot is a wrapper around slack.Client that posts and updates access
+// request messages.
+type Bot struct {
+	client      *slack.Client
+	channel     string
+	notifyOnly  bool
+	msgTemplate *template.Template
+	statusSink  status.Sink
+}
+
+// NewBot builds a Bot from the Slack portion of Config.
+func NewBot(conf *Config) *Bot {
+	client := slack.New(conf.Slack.Token, slack.OptionAPIURL(conf.Slack.APIURL))
+
+	tmplSrc := conf.Slack.MessageTemplate
+	if tmplSrc == "" {
+		tmplSrc = defaultMessageTemplate
+	}
+	msgTemplate, err := template.New("message").Parse(tmplSrc)
+	if err != nil {
+		// An operator-supplied template failed to parse; fall back to the
+		// default rather than letting a typo break every notification.
+		log.WithError(err).Error("Failed to parse Slack.MessageTemplate, using default")
+		msgTemplate = template.Must(template.New("message").Parse(defaultMessageTemplate))
+	}
+
+	return &Bot{
+		client:      client,
+		channel:     conf.Slack.Channel,
+		notifyOnly:  conf.Slack.NotifyOnly,
+		msgTemplate: msgTemplate,
+	}
+}
+
+// SetStatusSink wires a status.Sink that the bot will report its health to
+// on every Slack API call, deduplicated so a sustained outage doesn't spam
+// the sink with identical reports.
+func (b *Bot) SetStatusSink(sink status.Sink) {
+	b.statusSink = status.NewDedup(sink)
+}
+
+// Ping performs a lightweight round-trip to Slack (auth.test) and reports
+// the result to the configured status sink, if any.
+func (b *Bot) Ping(ctx context.Context) error {
+	_, err := b.client.AuthTestContext(ctx)
+	b.reportStatus(ctx, err)
+	return trace.Wrap(err)
+}
+
+func (b *Bot) reportStatus(ctx context.Context, err error) {
+	if b.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{LastReported: time.Now()}
+	if err == nil {
+		st.Code = status.Running
+	} else {
+		st.Code = status.FromSlackError(err)
+		st.LastError = err.Error()
+	}
+	if reportErr := b.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
+
+// PostMessage posts a new access-request message to the configured
+// channel and returns the PluginData needed to find it again later.
+func (b *Bot) PostMessage(ctx context.Context, reqID string, reqData requestData) (PluginData, error) {
+	_, channelID, timestamp, err := b.client.SendMessageContext(
+		ctx, b.channel,
+		slack.MsgOptionBlocks(b.blocks(reqID)...),
+		slack.MsgOptionAttachments(b.attachment(access.StatePending, reqData, "", "")),
+	)
+	b.reportStatus(ctx, err)
+	if err != nil {
+		return PluginData{}, trace.Wrap(err)
+	}
+	return PluginData{ChannelID: channelID, Timestamp: timestamp}, nil
+}
+
+// UpdateMessage edits the original request message in place to reflect
+// its final state: the color sidebar switches to green/red and the
+// approve/deny buttons are dropped.
+func (b *Bot) UpdateMessage(ctx context.Context, data PluginData, state access.State, reqData requestData, reviewer, reason string) error {
+	_, _, _, err := b.client.UpdateMessageContext(
+		ctx, data.ChannelID, data.Timestamp,
+		slack.MsgOptionBlocks(b.resolvedBlocks()...),
+		slack.MsgOptionAttachments(b.attachment(state, reqData, reviewer, reason)),
+	)
+	b.reportStatus(ctx, err)
+	return trace.Wrap(err)
+}
+
+// ExpireMessage edits the original request message to show it's expired:
+// the color sidebar switches to the neutral "pending" yellow (there's no
+// dedicated "expired" access.State to color by) and a thread reply records
+// what happened. Like jirabot's ExpireIssue, it's not currently wired to
+// anything: App's handleEvent treats access.OpDelete as a no-op, so this
+// is a hook waiting on watch-event support for expiry, not a live path.
+func (b *Bot) ExpireMessage(ctx context.Context, data PluginData) error {
+	_, _, _, err := b.client.UpdateMessageContext(
+		ctx, data.ChannelID, data.Timestamp,
+		slack.MsgOptionBlocks(b.resolvedBlocks()...),
+	)
+	b.reportStatus(ctx, err)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	_, _, _, err = b.client.SendMessageContext(
+		ctx, data.ChannelID,
+		slack.MsgOptionText("Request expired", false),
+		slack.MsgOptionTS(data.Timestamp),
+	)
+	b.reportStatus(ctx, err)
+	return trace.Wrap(err)
+}
+
+// PostThreadReply posts a follow-up reply, threaded under the original
+// request message, naming the reviewer who made the decision and their
+// reason.
+func (b *Bot) PostThreadReply(ctx context.Context, data PluginData, state access.State, reviewer, reason string) error {
+	verb := "approved"
+	if state == access.StateDenied {
+		verb = "denied"
+	}
+	text := fmt.Sprintf("Request %s by %s", verb, reviewer)
+	if reason != "" {
+		text = fmt.Sprintf("%s: %s", text, reason)
+	}
+	_, _, _, err := b.client.SendMessageContext(
+		ctx, data.ChannelID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(data.Timestamp),
+	)
+	b.reportStatus(ctx, err)
+	return trace.Wrap(err)
+}
+
+// headline renders the Slack.MessageTemplate against reqData.
+func (b *Bot) headline(reqData requestData) string {
+	var buf bytes.Buffer
+	data := messageTemplateData{
+		User:    reqData.user,
+		Roles:   reqData.roles,
+		Cluster: reqData.cluster,
+		TTL:     reqData.ttl,
+		Created: reqData.created,
+	}
+	if err := b.msgTemplate.Execute(&buf, data); err != nil {
+		log.WithError(err).Error("Failed to render Slack.MessageTemplate")
+		return fmt.Sprintf("%s is requesting roles %v", reqData.user, reqData.roles)
+	}
+	return buf.String()
+}
+
+// blocks builds the Block Kit layout for a pending access request: just
+// the approve/deny buttons, since the headline and structured fields live
+// in the accompanying attachment (Block Kit has no color sidebar).
+func (b *Bot) blocks(reqID string) []slack.Block {
+	if b.notifyOnly {
+		return nil
+	}
+	return []slack.Block{
+		slack.NewActionBlock(
+			"approve_or_deny",
+			slack.NewButtonBlockElement("approve_request", reqID, slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false)),
+			slack.NewButtonBlockElement("deny_request", reqID, slack.NewTextBlockObject(slack.PlainTextType, "Deny", false, false)),
+		),
+	}
+}
+
+// resolvedBlocks is the Block Kit layout for a request that's no longer
+// pending: no buttons left to click.
+func (b *Bot) resolvedBlocks() []slack.Block {
+	return nil
+}
+
+// attachment builds the colored, field-bearing Attachment that carries a
+// request's headline and metadata, following the pattern common Slack
+// logrus/notification hooks use to color-code severity.
+func (b *Bot) attachment(state access.State, reqData requestData, reviewer, reason string) slack.Attachment {
+	fields := []slack.AttachmentField{
+		{Title: "Requester", Value: reqData.user, Short: true},
+		{Title: "Roles", Value: fmt.Sprintf("%v", reqData.roles), Short: true},
+		{Title: "Cluster", Value: reqData.cluster, Short: true},
+		{Title: "TTL", Value: reqData.ttl.String(), Short: true},
+		{Title: "Created", Value: reqData.created.Format(time.RFC822), Short: true},
+	}
+	if reviewer != "" {
+		fields = append(fields, slack.AttachmentField{Title: "Reviewer", Value: reviewer, Short: true})
+	}
+	return slack.Attachment{
+		Color:  attachmentColor(state),
+		Text:   b.headline(reqData),
+		Fields: fields,
+	}
+}
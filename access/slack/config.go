@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"github.com/gravitational/teleport-plugins/access/clientauth"
+	"github.com/gravitational/teleport-plugins/access/notify"
+	"github.com/gravitational/teleport-plugins/utils"
+	"github.com/gravitational/trace"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultDir is the default directory the Slack plugin keeps generated
+// certificates (see utils.HTTP.EnsureCert) in.
+const DefaultDir = "/var/lib/teleport/plugins/slack"
+
+// Config is the Slack access-request plugin's configuration, loaded from
+// the YAML file passed on the command line.
+type Config struct {
+	Teleport struct {
+		AuthServer string `yaml:"auth-server"`
+		ClientCrt  string `yaml:"client-crt"`
+		ClientKey  string `yaml:"client-key"`
+		RootCAs    string `yaml:"root-cas"`
+	} `yaml:"teleport"`
+	Slack struct {
+		Token      string `yaml:"token"`
+		Secret     string `yaml:"signing-secret"`
+		Channel    string `yaml:"channel"`
+		APIURL     string `yaml:"api-url"`
+		NotifyOnly bool   `yaml:"notify-only"`
+		// Mode selects how the plugin receives interactive callbacks:
+		// "http" (default) runs an inbound webhook server, "socket" dials
+		// out over Slack's Socket Mode WebSocket instead, so no public URL
+		// needs to be exposed. See socket.go.
+		Mode string `yaml:"mode"`
+		// AppToken is the app-level token ("xapp-...") used to establish a
+		// Socket Mode connection. Required when Mode is "socket".
+		AppToken string `yaml:"app-token"`
+		// MessageTemplate is a Go text/template operators can use to
+		// customize the headline text of a request message. It's executed
+		// against messageTemplateData (User, Roles, Cluster, TTL, Created).
+		// Defaults to defaultMessageTemplate.
+		MessageTemplate string `yaml:"message-template"`
+	} `yaml:"slack"`
+	HTTP utils.HTTPConfig `yaml:"http"`
+
+	// ClientAuth optionally requires a verified client identity (beyond
+	// Slack's own request signature) on every interactive callback; see
+	// access/clientauth. Set HTTP.ClientCA to additionally require a
+	// verified mTLS client certificate at the listener level.
+	ClientAuth clientauth.Config `yaml:"client-auth"`
+
+	// Notifiers configures additional, send-only notification backends
+	// (see access/notify) to fan each request out to alongside Slack, e.g.
+	// to simultaneously post to a Teams channel. Approvals/denials still
+	// come back through Slack's own interactive buttons or Socket Mode
+	// connection: these notifiers have no inbound callback of their own.
+	Notifiers []notify.Config `yaml:"notifiers"`
+}
+
+// LoadConfig reads and parses a Config from the YAML file at path.
+func LoadConfig(path string) (*Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var conf Config
+	if err := yaml.Unmarshal(bytes, &conf); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if conf.Slack.APIURL == "" {
+		conf.Slack.APIURL = "https://slack.com/api/"
+	}
+	return &conf, nil
+}
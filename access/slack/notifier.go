@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gravitational/teleport-plugins/access"
+	"github.com/gravitational/teleport-plugins/access/notify"
+	"github.com/gravitational/trace"
+)
+
+// botNotifier adapts Bot to the notify.Notifier interface, so the Slack
+// plugin's own message is just one of the notifiers an App fans a request
+// out to.
+type botNotifier struct {
+	bot *Bot
+}
+
+func (n *botNotifier) Name() string { return "slack" }
+
+func (n *botNotifier) OnPending(ctx context.Context, req notify.RequestData, _ notify.NotifyData) (notify.NotifyData, error) {
+	data, err := n.bot.PostMessage(ctx, req.ID, fromNotifyRequestData(req))
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return notify.NotifyData(EncodePluginData(data)), nil
+}
+
+func (n *botNotifier) OnResolved(ctx context.Context, req notify.RequestData, data notify.NotifyData) (notify.NotifyData, error) {
+	pluginData := DecodePluginData(data)
+	state, err := stateFromString(req.State)
+	if err != nil {
+		return data, trace.Wrap(err)
+	}
+	if err := n.bot.UpdateMessage(ctx, pluginData, state, fromNotifyRequestData(req), req.Reviewer, req.Reason); err != nil {
+		return data, trace.Wrap(err)
+	}
+	return data, trace.Wrap(n.bot.PostThreadReply(ctx, pluginData, state, req.Reviewer, req.Reason))
+}
+
+// OnExpired is not currently called by App: handleEvent treats
+// access.OpDelete as a no-op, same as jirabot's OpDelete handling, pending
+// watch-event support for expiry. It's implemented anyway so that support
+// only has to be added in one place.
+func (n *botNotifier) OnExpired(ctx context.Context, req notify.RequestData, data notify.NotifyData) (notify.NotifyData, error) {
+	pluginData := DecodePluginData(data)
+	if pluginData.ChannelID == "" {
+		// We never actually posted a message for this request (e.g. it
+		// expired before this notifier was added); nothing to update.
+		return data, nil
+	}
+	return data, trace.Wrap(n.bot.ExpireMessage(ctx, pluginData))
+}
+
+func fromNotifyRequestData(req notify.RequestData) requestData {
+	return requestData{
+		user:    req.User,
+		roles:   req.Roles,
+		cluster: req.Cluster,
+		ttl:     req.TTL,
+		created: req.Created,
+	}
+}
+
+func stateFromString(s string) (access.State, error) {
+	switch s {
+	case "approved":
+		return access.StateApproved, nil
+	case "denied":
+		return access.StateDenied, nil
+	default:
+		return access.State(0), trace.BadParameter("unexpected resolved state %q", s)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"os/user"
@@ -19,6 +20,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/gravitational/teleport-plugins/access/integration"
+	"github.com/gravitational/teleport-plugins/access/notify"
 	"github.com/gravitational/teleport/lib/auth/testauthority"
 	"github.com/gravitational/teleport/lib/events"
 	"github.com/gravitational/teleport/lib/services"
@@ -178,7 +180,7 @@ func (s *SlackSuite) startApp(c *C) {
 	ok, err := s.app.WaitReady(ctx)
 	c.Assert(err, IsNil)
 	c.Assert(ok, Equals, true)
-	if s.publicURL == "" {
+	if s.publicURL == "" && s.app.PublicURL() != nil {
 		s.publicURL = s.app.PublicURL().String()
 	}
 }
@@ -203,7 +205,7 @@ func (s *SlackSuite) createExpiredAccessRequest(c *C) services.AccessRequest {
 func (s *SlackSuite) checkPluginData(c *C, reqID string) PluginData {
 	rawData, err := s.teleport.PollAccessRequestPluginData(s.ctx, "slack", reqID)
 	c.Assert(err, IsNil)
-	return DecodePluginData(rawData)
+	return DecodePluginData(notify.DecodeKeyed("slack", rawData))
 }
 
 func (s *SlackSuite) postCallbackAndCheck(c *C, actionID, reqID string, expectedStatus int) {
@@ -299,6 +301,19 @@ func (s *SlackSuite) TestSlackMessagePostingWithButtons(c *C) {
 	denyButton := blockAction.Elements.ElementSet[1].(*slack.ButtonBlockElement)
 	c.Assert(denyButton.ActionID, Equals, "deny_request")
 	c.Assert(denyButton.Value, Equals, request.GetName())
+
+	c.Assert(msg.Attachments, HasLen, 1)
+	attachment := msg.Attachments[0]
+	c.Assert(attachment.Color, Equals, "warning") // pending
+	fieldsByTitle := make(map[string]string)
+	for _, f := range attachment.Fields {
+		fieldsByTitle[f.Title] = f.Value
+	}
+	c.Assert(fieldsByTitle["Requester"], Equals, s.me.Username)
+	c.Assert(fieldsByTitle["Roles"], Equals, "[admin]")
+	c.Assert(fieldsByTitle["Cluster"], Not(Equals), "")
+	c.Assert(fieldsByTitle["TTL"], Not(Equals), "")
+	c.Assert(fieldsByTitle["Created"], Not(Equals), "")
 }
 
 // Tests if Interactive Mode posts Slack message with buttons correctly
@@ -392,6 +407,28 @@ func (s *SlackSuite) TestApproveExpired(c *C) {
 	c.Assert(msg1.Timestamp, Equals, msg2.Timestamp)
 }
 
+// Tests that approving/denying a request edits the original message in
+// place and threads a follow-up reply naming the reviewer.
+func (s *SlackSuite) TestSlackThreadedDecision(c *C) {
+	s.startApp(c)
+	request := s.createAccessRequest(c)
+	pluginData := s.checkPluginData(c, request.GetName())
+	msg1 := s.fetchSlackMessageAndCheck(c) // initial post
+
+	s.postCallbackAndCheck(c, "approve_request", request.GetName(), http.StatusOK)
+
+	msg2 := s.fetchSlackMessageAndCheck(c) // edited original message
+	c.Assert(msg2.Timestamp, Equals, pluginData.Timestamp)
+	c.Assert(msg2.Channel, Equals, pluginData.ChannelID)
+	c.Assert(msg2.Attachments, HasLen, 1)
+	c.Assert(msg2.Attachments[0].Color, Equals, "good") // approved
+
+	msg3 := s.fetchSlackMessageAndCheck(c) // threaded reply
+	c.Assert(msg3.ThreadTimestamp, Equals, msg1.Timestamp)
+	c.Assert(strings.Contains(msg3.Text, "approved"), Equals, true)
+	c.Assert(strings.Contains(msg3.Text, "spengler@ghostbusters.example.com"), Equals, true)
+}
+
 func (s *SlackSuite) TestDenyExpired(c *C) {
 	s.startApp(c)
 	request := s.createExpiredAccessRequest(c)
@@ -403,3 +440,76 @@ func (s *SlackSuite) TestDenyExpired(c *C) {
 	msg2 := s.fetchSlackMessageAndCheck(c)
 	c.Assert(msg1.Timestamp, Equals, msg2.Timestamp)
 }
+
+// Tests that a request is fanned out to an extra configured notifier (a
+// generic webhook, here) alongside Slack, and that the webhook hears about
+// the resolution too once the request is approved via Slack's buttons.
+func (s *SlackSuite) TestFanOutToExtraNotifier(c *C) {
+	events := make(chan string, 2)
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Event string `json:"event"`
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		c.Assert(json.Unmarshal(body, &payload), IsNil)
+		events <- payload.Event
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer webhookServer.Close()
+
+	s.appConfig.Notifiers = []notify.Config{
+		{Type: "webhook", Webhook: &notify.WebhookConfig{URL: webhookServer.URL}},
+	}
+	s.startApp(c)
+	request := s.createAccessRequest(c)
+	s.fetchSlackMessageAndCheck(c)
+
+	select {
+	case event := <-events:
+		c.Assert(event, Equals, "pending")
+	case <-time.After(time.Second):
+		c.Fatal("timed out waiting for webhook notifier")
+	}
+
+	s.postCallbackAndCheck(c, "approve_request", request.GetName(), http.StatusOK)
+	s.fetchSlackMessageAndCheck(c) // edited original message
+	s.fetchSlackMessageAndCheck(c) // threaded reply
+
+	select {
+	case event := <-events:
+		c.Assert(event, Equals, "resolved")
+	case <-time.After(time.Second):
+		c.Fatal("timed out waiting for webhook notifier's resolution event")
+	}
+}
+
+// TestPing checks that App.Ping (the same call Run makes periodically)
+// succeeds against a reachable Slack.
+func (s *SlackSuite) TestPing(c *C) {
+	s.startApp(c)
+	c.Assert(s.app.Ping(s.ctx), IsNil)
+}
+
+// TestSocketModeApproval drives a real App configured for Socket Mode
+// end-to-end: a fake Socket Mode server stands in for Slack's WebSocket,
+// and clicking Approve over it must reach App.onBlockAction and actually
+// call SetRequestState, just as the HTTP webhook transport does.
+func (s *SlackSuite) TestSocketModeApproval(c *C) {
+	fake := newFakeSocketModeServer()
+	defer fake.httpSrv.Close()
+
+	s.appConfig.Slack.Mode = "socket"
+	s.appConfig.Slack.AppToken = "xapp-test"
+	s.appConfig.Slack.APIURL = fake.apiURL()
+
+	s.startApp(c)
+	request := s.createAccessRequest(c)
+	s.checkPluginData(c, request.GetName()) // when plugin data created, we are sure that request is completely served.
+
+	fake.sendInteractive(c, "approve_request", request.GetName())
+
+	request, err := s.teleport.GetAccessRequest(s.ctx, request.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(request.GetState(), Equals, services.RequestState_APPROVED)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/trace"
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// socketModeURL is Slack's Socket Mode WebSocket endpoint. apps.connections.open
+// returns a short-lived URL rooted here; we dial that returned URL rather
+// than this constant directly — it's kept around for documentation.
+const socketModeURL = "wss://wss-primary.slack.com/link"
+
+// defaultAPIURL is the Slack Web API base used to reach
+// apps.connections.open when Config doesn't override it (tests point this
+// at a fake server the same way Bot's conf.Slack.APIURL does).
+const defaultAPIURL = "https://slack.com/api/"
+
+// Reconnect backoff bounds: a connection that keeps failing immediately
+// (a bad app token, Slack erroring out of apps.connections.open) backs
+// off up to reconnectBackoffMax instead of hammering Slack's API in a
+// tight loop. A connection that stays up at least reconnectResetAfter is
+// treated as healthy again and the backoff resets to its base.
+const (
+	reconnectBackoffBase = 500 * time.Millisecond
+	reconnectBackoffMax  = 30 * time.Second
+	reconnectResetAfter  = 10 * time.Second
+)
+
+// socketEnvelope is Slack's Socket Mode message envelope. `Payload` is
+// left raw so it can be re-decoded as the concrete type implied by Type.
+type socketEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type socketAck struct {
+	EnvelopeID string `json:"envelope_id"`
+}
+
+// SocketClient receives interactive callbacks over Slack's Socket Mode
+// WebSocket, as an alternative to WebhookServer's inbound HTTP transport.
+// It requires no public URL and no HMAC signature verification, since the
+// connection is outbound and authenticated by the app-level token.
+type SocketClient struct {
+	appToken      string
+	apiURL        string
+	httpClient    *http.Client
+	onBlockAction BlockActionFunc
+
+	statusSink status.Sink
+}
+
+// NewSocketClient builds a SocketClient that dispatches Block Kit button
+// clicks to onBlockAction, the same handler WebhookServer uses. apiURL is
+// the Slack Web API base to reach apps.connections.open at; pass "" to
+// use Slack's own API.
+func NewSocketClient(appToken, apiURL string, onBlockAction BlockActionFunc) *SocketClient {
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	return &SocketClient{
+		appToken:      appToken,
+		apiURL:        apiURL,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		onBlockAction: onBlockAction,
+	}
+}
+
+// SetStatusSink wires a status.Sink that the client will report to
+// whenever it fails to open or maintain the Socket Mode connection,
+// deduplicated so a sustained outage doesn't spam the sink with
+// identical reports.
+func (s *SocketClient) SetStatusSink(sink status.Sink) {
+	s.statusSink = status.NewDedup(sink)
+}
+
+// Run connects to Socket Mode and serves incoming envelopes until ctx is
+// canceled or the connection is dropped by Slack (a `disconnect`
+// envelope), in which case it reconnects, backing off between attempts
+// that fail in quick succession.
+func (s *SocketClient) Run(ctx context.Context) error {
+	backoff := reconnectBackoffBase
+	for {
+		connectedAt := time.Now()
+		if err := s.runOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.WithError(err).Warn("Socket Mode connection lost, reconnecting")
+			s.reportStatus(ctx, status.OtherError, err)
+
+			if time.Since(connectedAt) >= reconnectResetAfter {
+				backoff = reconnectBackoffBase
+			}
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return nil
+			}
+			if backoff < reconnectBackoffMax {
+				backoff *= 2
+				if backoff > reconnectBackoffMax {
+					backoff = reconnectBackoffMax
+				}
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *SocketClient) runOnce(ctx context.Context) error {
+	wssURL, err := s.openConnection(ctx)
+	if err != nil {
+		var apiErr *connectionsOpenError
+		if errors.As(err, &apiErr) {
+			s.reportStatus(ctx, status.FromSlackError(apiErr), err)
+		} else {
+			// A network error, timeout, or non-2xx response from
+			// apps.connections.open itself isn't a credentials problem.
+			s.reportStatus(ctx, status.OtherError, err)
+		}
+		return trace.Wrap(err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wssURL, nil)
+	if err != nil {
+		s.reportStatus(ctx, status.OtherError, err)
+		return trace.Wrap(err)
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	for {
+		var env socketEnvelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return trace.Wrap(err)
+		}
+		switch env.Type {
+		case "hello":
+			log.Debug("Socket Mode connection established")
+			s.reportStatus(ctx, status.Running, nil)
+		case "disconnect":
+			return trace.Errorf("server requested disconnect")
+		case "interactive":
+			if err := s.handleInteractive(ctx, env); err != nil {
+				log.WithError(err).Error("Failed to process interaction callback")
+				s.reportStatus(ctx, status.FromSlackError(err), err)
+			} else {
+				s.reportStatus(ctx, status.Running, nil)
+			}
+			if err := conn.WriteJSON(socketAck{EnvelopeID: env.EnvelopeID}); err != nil {
+				return trace.Wrap(err)
+			}
+		default:
+			// Unknown envelope types are still ACKed so Slack doesn't
+			// retry them; we just don't know how to act on them.
+			if env.EnvelopeID != "" {
+				if err := conn.WriteJSON(socketAck{EnvelopeID: env.EnvelopeID}); err != nil {
+					return trace.Wrap(err)
+				}
+			}
+		}
+	}
+}
+
+func (s *SocketClient) handleInteractive(ctx context.Context, env socketEnvelope) error {
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal(env.Payload, &callback); err != nil {
+		return trace.Wrap(err)
+	}
+	for _, action := range callback.ActionCallback.BlockActions {
+		if err := s.onBlockAction(ctx, action, callback.User.ID); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// connectionsOpenError is returned by openConnection when
+// apps.connections.open itself rejected the request (a `{"ok": false,
+// "error": "..."}` response), so runOnce can classify it with
+// status.FromSlackError the same way a Slack API error is classified
+// anywhere else, distinct from a network error, timeout, or malformed
+// response, which is always status.OtherError.
+type connectionsOpenError struct {
+	slackErr string
+}
+
+func (e *connectionsOpenError) Error() string { return e.slackErr }
+
+// openConnection calls apps.connections.open to exchange the app-level
+// token for a one-time Socket Mode WebSocket URL.
+func (s *SocketClient) openConnection(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL+"apps.connections.open", nil)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.appToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		URL   string `json:"url"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", trace.Wrap(err)
+	}
+	if !result.OK {
+		return "", &connectionsOpenError{slackErr: result.Error}
+	}
+	return result.URL, nil
+}
+
+// reportStatus pushes a plugin health update to the configured status
+// sink, if any. It's a no-op when no sink has been set.
+func (s *SocketClient) reportStatus(ctx context.Context, code status.Code, err error) {
+	if s.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{Code: code, LastReported: time.Now()}
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	if reportErr := s.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
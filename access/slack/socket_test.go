@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/nlopes/slack"
+
+	. "gopkg.in/check.v1"
+)
+
+// fakeSocketModeServer serves both apps.connections.open and the
+// WebSocket it hands back, standing in for Slack's real Socket Mode
+// infrastructure in tests. It hands the established connection to connCh
+// as soon as the handshake completes, so callers can push interactive
+// envelopes for requests whose IDs aren't known until runtime.
+type fakeSocketModeServer struct {
+	httpSrv  *httptest.Server
+	upgrader websocket.Upgrader
+	connCh   chan *websocket.Conn
+}
+
+func newFakeSocketModeServer() *fakeSocketModeServer {
+	f := &fakeSocketModeServer{connCh: make(chan *websocket.Conn, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", f.handleOpen)
+	mux.HandleFunc("/link", f.handleLink)
+	f.httpSrv = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeSocketModeServer) apiURL() string {
+	return f.httpSrv.URL + "/"
+}
+
+func (f *fakeSocketModeServer) handleOpen(rw http.ResponseWriter, r *http.Request) {
+	wsURL := "ws" + strings.TrimPrefix(f.httpSrv.URL, "http") + "/link"
+	json.NewEncoder(rw).Encode(map[string]interface{}{"ok": true, "url": wsURL})
+}
+
+func (f *fakeSocketModeServer) handleLink(rw http.ResponseWriter, r *http.Request) {
+	conn, err := f.upgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(map[string]string{"type": "hello"})
+	f.connCh <- conn
+
+	for {
+		var ack socketAck
+		if err := conn.ReadJSON(&ack); err != nil {
+			return
+		}
+	}
+}
+
+// sendInteractive waits for the Socket Mode connection to be established
+// and pushes a fake button-click envelope over it, as Slack would when a
+// user clicks an Approve/Deny button. The envelope's "payload" is the
+// InteractionCallback itself, matching Slack's real wire format.
+func (f *fakeSocketModeServer) sendInteractive(c *C, actionID, reqID string) {
+	var conn *websocket.Conn
+	select {
+	case conn = <-f.connCh:
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timed out waiting for Socket Mode connection")
+	}
+	f.connCh <- conn
+
+	cb := slack.InteractionCallback{
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: actionID, Value: reqID}},
+		},
+	}
+	cb.User.ID = "U000"
+	payload, err := json.Marshal(cb)
+	c.Assert(err, IsNil)
+	env := map[string]json.RawMessage{
+		"type":        json.RawMessage(`"interactive"`),
+		"envelope_id": json.RawMessage(`"env1"`),
+		"payload":     payload,
+	}
+	c.Assert(conn.WriteJSON(env), IsNil)
+}
+
+type SocketSuite struct{}
+
+var _ = Suite(&SocketSuite{})
+
+// TestSocketModeDispatchesBlockActions dials a fake Socket Mode server,
+// receives an interactive envelope, and asserts it's dispatched to the
+// same onBlockAction handler the HTTP webhook transport uses, then acked.
+func (s *SocketSuite) TestSocketModeDispatchesBlockActions(c *C) {
+	type seen struct {
+		actionID, reqID, userID string
+	}
+	seenCh := make(chan seen, 1)
+
+	client := NewSocketClient("xapp-test", "", func(ctx context.Context, action *slack.BlockAction, userID string) error {
+		seenCh <- seen{action.ActionID, action.Value, userID}
+		return nil
+	})
+
+	fake := newFakeSocketModeServer()
+	defer fake.httpSrv.Close()
+	client.apiURL = fake.apiURL()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	go client.Run(ctx)
+	fake.sendInteractive(c, "approve_request", "req-123")
+
+	select {
+	case got := <-seenCh:
+		c.Assert(got.actionID, Equals, "approve_request")
+		c.Assert(got.reqID, Equals, "req-123")
+		c.Assert(got.userID, Equals, "U000")
+	case <-ctx.Done():
+		c.Fatalf("timed out waiting for block action dispatch")
+	}
+}
+
+// TestSocketModeReconnectBacksOff asserts that a persistently failing
+// apps.connections.open doesn't get hammered in a tight loop: the time
+// between the first and fourth attempt must be at least the sum of the
+// backoff delays that should've elapsed by then.
+func (s *SocketSuite) TestSocketModeReconnectBacksOff(c *C) {
+	var attempts int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&attempts, 1)
+		json.NewEncoder(rw).Encode(map[string]interface{}{"ok": false, "error": "invalid_auth"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := NewSocketClient("xapp-test", srv.URL+"/", func(ctx context.Context, action *slack.BlockAction, userID string) error {
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectBackoffBase+2*reconnectBackoffBase+500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	client.Run(ctx)
+	elapsed := time.Since(start)
+
+	seen := atomic.LoadInt64(&attempts)
+	c.Assert(seen >= 2, Equals, true)
+	// With backoff doubling from reconnectBackoffBase, at least
+	// reconnectBackoffBase must have elapsed before the second attempt.
+	c.Assert(elapsed >= reconnectBackoffBase, Equals, true)
+}
+
+// TestSocketModeOpenConnectionStatus asserts that runOnce classifies a
+// rejected apps.connections.open (e.g. a bad app token) as
+// status.Unauthorized, while a transport failure reaching it (here, a
+// closed listener) is reported as status.OtherError rather than being
+// mistaken for a credentials problem.
+func (s *SocketSuite) TestSocketModeOpenConnectionStatus(c *C) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apps.connections.open", func(rw http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(rw).Encode(map[string]interface{}{"ok": false, "error": "invalid_auth"})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := NewSocketClient("xapp-test", srv.URL+"/", func(ctx context.Context, action *slack.BlockAction, userID string) error {
+		return nil
+	})
+	sink := status.NewFakeSink()
+	client.SetStatusSink(sink)
+
+	c.Assert(client.runOnce(context.Background()), NotNil)
+	last, ok := sink.Last()
+	c.Assert(ok, Equals, true)
+	c.Assert(last.Code, Equals, status.Unauthorized)
+
+	// Now point at a closed listener: not a credentials problem, just
+	// unreachable.
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+
+	client = NewSocketClient("xapp-test", unreachable.URL+"/", func(ctx context.Context, action *slack.BlockAction, userID string) error {
+		return nil
+	})
+	sink = status.NewFakeSink()
+	client.SetStatusSink(sink)
+
+	c.Assert(client.runOnce(context.Background()), NotNil)
+	last, ok = sink.Last()
+	c.Assert(ok, Equals, true)
+	c.Assert(last.Code, Equals, status.OtherError)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gravitational/teleport-plugins/access/clientauth"
+	"github.com/gravitational/teleport-plugins/access/status"
+	"github.com/gravitational/teleport-plugins/utils"
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+	"github.com/nlopes/slack"
+	log "github.com/sirupsen/logrus"
+)
+
+// BlockActionFunc handles a single approve/deny button click.
+type BlockActionFunc func(ctx context.Context, action *slack.BlockAction, userID string) error
+
+// WebhookServer is a wrapper around http.Server that receives Slack's
+// interactive Block Kit callbacks over its classic HTTP transport: Slack
+// POSTs a signed, form-encoded `payload` to our public URL whenever a user
+// clicks a button. See socket.go for the Socket Mode alternative, which
+// receives the same callbacks over a WebSocket instead.
+type WebhookServer struct {
+	http          *utils.HTTP
+	secret        string
+	notifyOnly    bool
+	onBlockAction BlockActionFunc
+
+	statusSink status.Sink
+}
+
+func NewWebhookServer(conf utils.HTTPConfig, authConf clientauth.Config, secret string, notifyOnly bool, onBlockAction BlockActionFunc) (*WebhookServer, error) {
+	httpSrv, err := utils.NewHTTP(conf)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	srv := &WebhookServer{
+		http:          httpSrv,
+		secret:        secret,
+		notifyOnly:    notifyOnly,
+		onBlockAction: onBlockAction,
+	}
+	handler, err := clientauth.Middleware(authConf, srv.processCallback)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	httpSrv.POST("/", handler)
+	return srv, nil
+}
+
+// SetStatusSink wires a status.Sink that the server will report to
+// whenever it fails to process a callback, deduplicated so a sustained
+// failure doesn't spam the sink with identical reports.
+func (s *WebhookServer) SetStatusSink(sink status.Sink) {
+	s.statusSink = status.NewDedup(sink)
+}
+
+func (s *WebhookServer) ServiceJob() utils.ServiceJob {
+	return s.http.ServiceJob()
+}
+
+func (s *WebhookServer) BaseURL() *url.URL {
+	return s.http.BaseURL()
+}
+
+func (s *WebhookServer) EnsureCert() error {
+	return s.http.EnsureCert(DefaultDir + "/server")
+}
+
+func (s *WebhookServer) processCallback(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*5)
+	defer cancel()
+
+	if s.notifyOnly {
+		http.Error(rw, "", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.WithError(err).Error("Failed to read callback payload")
+		http.Error(rw, "", http.StatusInternalServerError)
+		return
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, s.secret)
+	if err != nil {
+		log.WithError(err).Error("Failed to build Slack signature verifier")
+		http.Error(rw, "", http.StatusInternalServerError)
+		return
+	}
+	if _, err := verifier.Write(body); err != nil {
+		log.WithError(err).Error("Failed to hash callback payload")
+		http.Error(rw, "", http.StatusInternalServerError)
+		return
+	}
+	if err := verifier.Ensure(); err != nil {
+		log.WithError(err).Error("Slack signature verification failed")
+		s.reportStatus(ctx, status.Unauthorized, trace.Wrap(err, "Slack signature verification failed"))
+		http.Error(rw, "", http.StatusUnauthorized)
+		return
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		log.WithError(err).Error("Failed to parse callback payload")
+		http.Error(rw, "", http.StatusBadRequest)
+		return
+	}
+
+	var cb slack.InteractionCallback
+	if err := json.Unmarshal([]byte(values.Get("payload")), &cb); err != nil {
+		log.WithError(err).Error("Failed to parse interaction callback")
+		http.Error(rw, "", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.dispatch(ctx, &cb); err != nil {
+		log.WithError(err).Error("Failed to process interaction callback")
+		log.Debugf("%v", trace.DebugReport(err))
+		var code int
+		switch {
+		case utils.IsCanceled(err) || utils.IsDeadline(err):
+			code = http.StatusServiceUnavailable
+		default:
+			code = http.StatusInternalServerError
+		}
+		s.reportStatus(ctx, status.FromHTTPStatusCode(code), err)
+		http.Error(rw, "", code)
+		return
+	}
+	s.reportStatus(ctx, status.Running, nil)
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (s *WebhookServer) dispatch(ctx context.Context, cb *slack.InteractionCallback) error {
+	for _, action := range cb.ActionCallback.BlockActions {
+		if err := s.onBlockAction(ctx, action, cb.User.ID); err != nil {
+			return trace.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// reportStatus pushes a plugin health update to the configured status
+// sink, if any. It's a no-op when no sink has been set.
+func (s *WebhookServer) reportStatus(ctx context.Context, code status.Code, err error) {
+	if s.statusSink == nil {
+		return
+	}
+	st := status.PluginStatus{Code: code, LastReported: time.Now()}
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	if reportErr := s.statusSink.Report(ctx, st); reportErr != nil {
+		log.WithError(reportErr).Error("Failed to report plugin status")
+	}
+}
@@ -0,0 +1,46 @@
+package status
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeSink is a Sink that records every status it receives, for use in
+// plugin tests that want to assert a particular failure was classified
+// correctly (e.g. "an invalid Slack token surfaces Unauthorized").
+type FakeSink struct {
+	mu       sync.Mutex
+	statuses []PluginStatus
+}
+
+// NewFakeSink returns an empty FakeSink.
+func NewFakeSink() *FakeSink {
+	return &FakeSink{}
+}
+
+func (s *FakeSink) Report(ctx context.Context, status PluginStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses = append(s.statuses, status)
+	return nil
+}
+
+// Last returns the most recently reported status and true, or the zero
+// value and false if nothing has been reported yet.
+func (s *FakeSink) Last() (PluginStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.statuses) == 0 {
+		return PluginStatus{}, false
+	}
+	return s.statuses[len(s.statuses)-1], true
+}
+
+// All returns every status reported so far, in order.
+func (s *FakeSink) All() []PluginStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]PluginStatus, len(s.statuses))
+	copy(out, s.statuses)
+	return out
+}
@@ -0,0 +1,40 @@
+package status
+
+import (
+	"errors"
+
+	"github.com/nlopes/slack"
+)
+
+// unauthorizedSlackErrors are the `error` field values nlopes/slack surfaces
+// for API calls (chat.postMessage, auth.test, ...) whose response body was
+// `{"ok": false, "error": "..."}` and indicate our credentials are no good,
+// as opposed to a transient or request-specific failure.
+var unauthorizedSlackErrors = map[string]bool{
+	"invalid_auth":     true,
+	"not_authed":       true,
+	"account_inactive": true,
+	"token_revoked":    true,
+}
+
+// FromSlackError classifies an error returned by an nlopes/slack API call
+// (e.g. chat.postMessage, auth.test) into a Code. It understands the SDK's
+// RateLimitedError and the "invalid_auth"/"not_authed"/"token_revoked"
+// `error` values Slack sends back in an otherwise-200 response; anything
+// else falls back to OtherError.
+func FromSlackError(err error) Code {
+	if err == nil {
+		return Running
+	}
+
+	var rateLimitErr *slack.RateLimitedError
+	if errors.As(err, &rateLimitErr) {
+		return RateLimited
+	}
+
+	if unauthorizedSlackErrors[err.Error()] {
+		return Unauthorized
+	}
+
+	return OtherError
+}
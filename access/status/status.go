@@ -0,0 +1,110 @@
+// Package status provides a small cross-cutting subsystem that access
+// plugins (Slack, JIRA, ...) can use to report their health back to
+// Teleport. Each plugin pushes a PluginStatus into a Sink every time it
+// talks to its upstream API (Slack, JIRA, ...); the default Sink forwards
+// the result to Teleport's plugin-status API so operators can see
+// "is this plugin actually working" from `tctl` / the UI.
+package status
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Code is a normalized plugin health code. It intentionally collapses
+// transport-specific errors (HTTP status codes, typed SDK errors) into a
+// small enum so that Teleport doesn't need to know anything about Slack
+// or JIRA to render a status.
+type Code int
+
+const (
+	// Running indicates that the last call to the upstream API succeeded.
+	Running Code = iota
+	// Unauthorized indicates that the upstream API rejected our credentials.
+	Unauthorized
+	// RateLimited indicates that the upstream API responded with 429 Too
+	// Many Requests.
+	RateLimited
+	// OtherError indicates some other, unclassified failure talking to the
+	// upstream API.
+	OtherError
+)
+
+func (c Code) String() string {
+	switch c {
+	case Running:
+		return "RUNNING"
+	case Unauthorized:
+		return "UNAUTHORIZED"
+	case RateLimited:
+		return "RATE_LIMITED"
+	case OtherError:
+		return "OTHER_ERROR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// PluginStatus is a point-in-time health report for a plugin.
+type PluginStatus struct {
+	Code Code
+	// LastError is the error that produced Code, if any. Empty when Code
+	// is Running.
+	LastError string
+	// LastReported is when this status was last pushed to the Sink.
+	LastReported time.Time
+}
+
+// Sink receives plugin health updates. Implementations must be safe for
+// concurrent use, since call sites report from several goroutines (event
+// handlers, webhook handlers, the periodic Ping loop, ...).
+type Sink interface {
+	Report(ctx context.Context, status PluginStatus) error
+}
+
+// FromHTTPStatusCode maps a plain HTTP status code to a Code. It's the
+// right helper for transports (like a generic webhook or a REST call)
+// that don't have a richer, SDK-specific error to inspect.
+func FromHTTPStatusCode(code int) Code {
+	switch {
+	case code == http.StatusOK || code == http.StatusNoContent:
+		return Running
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return Unauthorized
+	case code == http.StatusTooManyRequests:
+		return RateLimited
+	default:
+		return OtherError
+	}
+}
+
+// Dedup wraps a Sink and suppresses consecutive reports that carry the
+// same Code and LastError, so a call site can report on every API call
+// without spamming the sink when the plugin is stuck in one failure mode.
+type Dedup struct {
+	sink Sink
+
+	mu   sync.Mutex
+	last PluginStatus
+	seen bool
+}
+
+// NewDedup returns a Sink that forwards to sink, dropping consecutive
+// reports that are identical to the last one forwarded.
+func NewDedup(sink Sink) *Dedup {
+	return &Dedup{sink: sink}
+}
+
+func (d *Dedup) Report(ctx context.Context, status PluginStatus) error {
+	d.mu.Lock()
+	if d.seen && d.last.Code == status.Code && d.last.LastError == status.LastError {
+		d.mu.Unlock()
+		return nil
+	}
+	d.last = status
+	d.seen = true
+	d.mu.Unlock()
+	return d.sink.Report(ctx, status)
+}
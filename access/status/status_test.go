@@ -0,0 +1,57 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nlopes/slack"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestStatus(t *testing.T) { TestingT(t) }
+
+type StatusSuite struct{}
+
+var _ = Suite(&StatusSuite{})
+
+func (s *StatusSuite) TestFromSlackErrorUnauthorized(c *C) {
+	code := FromSlackError(errors.New("invalid_auth"))
+	c.Assert(code, Equals, Unauthorized)
+}
+
+func (s *StatusSuite) TestFromSlackErrorRateLimited(c *C) {
+	code := FromSlackError(&slack.RateLimitedError{})
+	c.Assert(code, Equals, RateLimited)
+}
+
+func (s *StatusSuite) TestFromSlackErrorOther(c *C) {
+	code := FromSlackError(errors.New("channel_not_found"))
+	c.Assert(code, Equals, OtherError)
+}
+
+func (s *StatusSuite) TestFromHTTPStatusCode(c *C) {
+	c.Assert(FromHTTPStatusCode(200), Equals, Running)
+	c.Assert(FromHTTPStatusCode(401), Equals, Unauthorized)
+	c.Assert(FromHTTPStatusCode(429), Equals, RateLimited)
+	c.Assert(FromHTTPStatusCode(500), Equals, OtherError)
+}
+
+func (s *StatusSuite) TestDedup(c *C) {
+	fake := NewFakeSink()
+	dedup := NewDedup(fake)
+	ctx := context.Background()
+
+	c.Assert(dedup.Report(ctx, PluginStatus{Code: Running}), IsNil)
+	c.Assert(dedup.Report(ctx, PluginStatus{Code: Running}), IsNil)
+	c.Assert(dedup.Report(ctx, PluginStatus{Code: Unauthorized, LastError: "invalid_auth"}), IsNil)
+	c.Assert(dedup.Report(ctx, PluginStatus{Code: Unauthorized, LastError: "invalid_auth"}), IsNil)
+	c.Assert(dedup.Report(ctx, PluginStatus{Code: Running}), IsNil)
+
+	all := fake.All()
+	c.Assert(all, HasLen, 3)
+	c.Assert(all[0].Code, Equals, Running)
+	c.Assert(all[1].Code, Equals, Unauthorized)
+	c.Assert(all[2].Code, Equals, Running)
+}
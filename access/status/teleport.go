@@ -0,0 +1,43 @@
+package status
+
+import (
+	"context"
+
+	"github.com/gravitational/trace"
+)
+
+// PluginStatusSetter is implemented by auth clients that can persist
+// plugin status in Teleport (the plugin-status API added alongside this
+// package). Not every access.Client the plugins are built against will
+// implement it yet, so DefaultSink degrades to a no-op when it doesn't.
+type PluginStatusSetter interface {
+	SetPluginStatus(ctx context.Context, plugin string, code string, lastError string) error
+}
+
+// DefaultSink reports plugin status into Teleport via SetPluginStatus,
+// when the underlying client supports it. It's the Sink every plugin's
+// App should wire in by default; FakeSink exists for tests that want to
+// assert on reported statuses directly instead.
+type DefaultSink struct {
+	plugin string
+	client PluginStatusSetter
+}
+
+// NewDefaultSink returns a Sink that reports status for the named plugin
+// (e.g. "slack", "jira") using client. If client does not implement
+// PluginStatusSetter, Report is a no-op that never fails: older Teleport
+// clusters without the plugin-status API shouldn't break the plugin.
+func NewDefaultSink(plugin string, client interface{}) *DefaultSink {
+	setter, _ := client.(PluginStatusSetter)
+	return &DefaultSink{plugin: plugin, client: setter}
+}
+
+func (s *DefaultSink) Report(ctx context.Context, st PluginStatus) error {
+	if s.client == nil {
+		return nil
+	}
+	if err := s.client.SetPluginStatus(ctx, s.plugin, st.Code.String(), st.LastError); err != nil {
+		return trace.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"context"
+	"errors"
+)
+
+// IsCanceled returns true if err is (or wraps) context.Canceled.
+func IsCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// IsDeadline returns true if err is (or wraps) context.DeadlineExceeded.
+func IsDeadline(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,271 @@
+// Package utils collects small helpers shared by every access plugin:
+// right now, just the HTTP server wrapper used to receive webhook
+// callbacks (Slack interactive buttons, JIRA/GitLab webhooks, ...).
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/julienschmidt/httprouter"
+)
+
+// selfSignedCertTTL is how long a certificate generated by EnsureCert is
+// valid for.
+const selfSignedCertTTL = 10 * 365 * 24 * time.Hour
+
+// HTTPConfig is the common "http:" section every plugin's Config embeds
+// to configure its inbound webhook/callback server.
+type HTTPConfig struct {
+	// ListenAddr is the local address the server binds to, e.g. ":8081".
+	ListenAddr string `yaml:"listen-addr"`
+	// PublicAddr is the externally-reachable address operators register
+	// with Slack/JIRA/GitLab. Defaults to ListenAddr when empty.
+	PublicAddr string `yaml:"public-addr"`
+	// Insecure serves plain HTTP instead of HTTPS. Only meant for tests
+	// and for deployments that terminate TLS at an upstream proxy.
+	Insecure bool `yaml:"insecure"`
+	// KeyFile/CertFile are an operator-supplied TLS keypair. When either is
+	// empty (and Insecure isn't set), EnsureCert must be called to
+	// generate and load a self-signed fallback before the server starts
+	// accepting connections.
+	KeyFile  string `yaml:"https-key-file"`
+	CertFile string `yaml:"https-cert-file"`
+	// ClientCA is a PEM bundle of CA certificates. When set, the server
+	// requires and verifies a client certificate signed by one of them
+	// (tls.RequireAndVerifyClientCert) on every connection.
+	ClientCA string `yaml:"client-ca"`
+}
+
+// ServiceJob is a blocking unit of work that runs until ctx is canceled
+// or it fails.
+type ServiceJob func(ctx context.Context) error
+
+// HTTP is a small wrapper around http.Server and httprouter.Router that
+// binds its listener eagerly (so BaseURL is valid as soon as NewHTTP
+// returns) and defers actually serving requests to ServiceJob. On a
+// non-Insecure server whose HTTPConfig has no keypair of its own, the
+// listener's certificate is loaded lazily: NewHTTP leaves it unset and
+// EnsureCert must be called (generating a self-signed fallback) before
+// ServiceJob runs, or the first handshake will fail.
+type HTTP struct {
+	conf     HTTPConfig
+	router   *httprouter.Router
+	listener net.Listener
+	baseURL  *url.URL
+	server   *http.Server
+
+	certMu sync.Mutex
+	cert   *tls.Certificate
+}
+
+// NewHTTP binds conf.ListenAddr and returns an HTTP ready to have routes
+// registered on it via POST.
+func NewHTTP(conf HTTPConfig) (*HTTP, error) {
+	router := httprouter.New()
+	server := &http.Server{Handler: router}
+
+	h := &HTTP{conf: conf, router: router, server: server}
+
+	if conf.Insecure {
+		listener, err := net.Listen("tcp", conf.ListenAddr)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		h.listener = listener
+	} else {
+		tlsConf, err := h.tlsConfig()
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		listener, err := tls.Listen("tcp", conf.ListenAddr, tlsConf)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		h.listener = listener
+
+		if conf.CertFile != "" && conf.KeyFile != "" {
+			if err := h.loadCert(conf.CertFile, conf.KeyFile); err != nil {
+				return nil, trace.Wrap(err)
+			}
+		}
+	}
+
+	scheme := "https"
+	if conf.Insecure {
+		scheme = "http"
+	}
+	publicAddr := conf.PublicAddr
+	if publicAddr == "" {
+		publicAddr = h.listener.Addr().String()
+	}
+	baseURL, err := url.Parse(scheme + "://" + publicAddr)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	h.baseURL = baseURL
+
+	return h, nil
+}
+
+// tlsConfig builds the listener's tls.Config. Its certificate is supplied
+// by getCertificate rather than set directly here, since an operator who
+// omitted https-cert-file/https-key-file hasn't provided one yet: EnsureCert
+// loads it (or generates a self-signed fallback) after NewHTTP returns, via
+// loadCert.
+func (h *HTTP) tlsConfig() (*tls.Config, error) {
+	tlsConf := &tls.Config{GetCertificate: h.getCertificate}
+
+	if h.conf.ClientCA != "" {
+		caPEM, err := ioutil.ReadFile(h.conf.ClientCA)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, trace.BadParameter("no certificates found in %s", h.conf.ClientCA)
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConf, nil
+}
+
+// getCertificate backs tlsConfig's GetCertificate: it's consulted at each
+// TLS handshake, by which point loadCert must already have been called
+// (via NewHTTP or EnsureCert) or the handshake fails.
+func (h *HTTP) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	h.certMu.Lock()
+	defer h.certMu.Unlock()
+	if h.cert == nil {
+		return nil, trace.BadParameter("no TLS certificate loaded; call EnsureCert before serving")
+	}
+	return h.cert, nil
+}
+
+// loadCert reads certFile/keyFile and installs them as the listener's
+// certificate for any handshake from this point on.
+func (h *HTTP) loadCert(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	h.certMu.Lock()
+	h.cert = &cert
+	h.certMu.Unlock()
+	return nil
+}
+
+// POST registers a handler for POST requests to path.
+func (h *HTTP) POST(path string, handle httprouter.Handle) {
+	h.router.POST(path, handle)
+}
+
+// BaseURL is the externally-reachable URL of this server.
+func (h *HTTP) BaseURL() *url.URL {
+	return h.baseURL
+}
+
+// EnsureCert is a no-op when CertFile/KeyFile were already set in
+// HTTPConfig (NewHTTP has loaded them already), or the server is
+// Insecure. Otherwise it loads the keypair at pathPrefix+".crt"/".key",
+// generating a self-signed one first if nothing is there yet, so the
+// caller must call this before ServiceJob: the listener has no
+// certificate to present until it does.
+func (h *HTTP) EnsureCert(pathPrefix string) error {
+	if h.conf.Insecure || (h.conf.CertFile != "" && h.conf.KeyFile != "") {
+		return nil
+	}
+	certFile, keyFile := pathPrefix+".crt", pathPrefix+".key"
+	if err := generateSelfSignedCert(certFile, keyFile); err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(h.loadCert(certFile, keyFile))
+}
+
+// generateSelfSignedCert writes a self-signed keypair to certFile/keyFile,
+// unless both already exist, in which case it leaves them alone so a
+// plugin restart keeps presenting the same certificate instead of
+// invalidating anything that pinned it.
+func generateSelfSignedCert(certFile, keyFile string) error {
+	if _, err := os.Stat(certFile); err == nil {
+		if _, err := os.Stat(keyFile); err == nil {
+			return nil
+		}
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "teleport-plugin"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(selfSignedCertTTL),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+
+	certOut, err := os.OpenFile(certFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		return trace.Wrap(err)
+	}
+
+	keyOut, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+		return trace.Wrap(err)
+	}
+
+	return nil
+}
+
+// ServiceJob serves requests on the bound listener until ctx is canceled.
+func (h *HTTP) ServiceJob() ServiceJob {
+	return func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() { errCh <- h.server.Serve(h.listener) }()
+
+		select {
+		case <-ctx.Done():
+			return trace.Wrap(h.server.Close())
+		case err := <-errCh:
+			if err == http.ErrServerClosed {
+				return nil
+			}
+			return trace.Wrap(err)
+		}
+	}
+}
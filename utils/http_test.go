@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	. "gopkg.in/check.v1"
+)
+
+func TestHTTP(t *testing.T) { TestingT(t) }
+
+type HTTPSuite struct{}
+
+var _ = Suite(&HTTPSuite{})
+
+// genCert generates a self-signed keypair, optionally signed by a
+// supplied CA, and writes both the cert and key as PEM to temp files.
+func genCert(c *C, signerCert *x509.Certificate, signerKey *ecdsa.PrivateKey, isCA bool) (certFile, keyFile string, cert *x509.Certificate, key *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         isCA,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	parent, parentKey := template, key
+	if signerCert != nil {
+		parent, parentKey = signerCert, signerKey
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	c.Assert(err, IsNil)
+
+	cert, err = x509.ParseCertificate(derBytes)
+	c.Assert(err, IsNil)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+
+	certOut, err := ioutil.TempFile("", "cert.*.pem")
+	c.Assert(err, IsNil)
+	_, err = certOut.Write(certPEM)
+	c.Assert(err, IsNil)
+	c.Assert(certOut.Close(), IsNil)
+
+	keyOut, err := ioutil.TempFile("", "key.*.pem")
+	c.Assert(err, IsNil)
+	_, err = keyOut.Write(keyPEM)
+	c.Assert(err, IsNil)
+	c.Assert(keyOut.Close(), IsNil)
+
+	return certOut.Name(), keyOut.Name(), cert, key
+}
+
+func (s *HTTPSuite) TestClientCertEnforcement(c *C) {
+	// Build a CA, a server cert signed by it, a "good" client cert signed
+	// by it, and an unrelated "bad" client cert signed by a different CA.
+	caCertFile, caKeyFile, caCert, caKey := genCert(c, nil, nil, true)
+	defer os.Remove(caCertFile)
+	defer os.Remove(caKeyFile)
+
+	serverCertFile, serverKeyFile, _, _ := genCert(c, caCert, caKey, false)
+	defer os.Remove(serverCertFile)
+	defer os.Remove(serverKeyFile)
+
+	goodClientCertFile, goodClientKeyFile, _, _ := genCert(c, caCert, caKey, false)
+	defer os.Remove(goodClientCertFile)
+	defer os.Remove(goodClientKeyFile)
+
+	_, _, otherCACert, otherCAKey := genCert(c, nil, nil, true)
+	badClientCertFile, badClientKeyFile, _, _ := genCert(c, otherCACert, otherCAKey, false)
+	defer os.Remove(badClientCertFile)
+	defer os.Remove(badClientKeyFile)
+
+	caPEM, err := ioutil.ReadFile(caCertFile)
+	c.Assert(err, IsNil)
+
+	h, err := NewHTTP(HTTPConfig{
+		ListenAddr: "127.0.0.1:0",
+		CertFile:   serverCertFile,
+		KeyFile:    serverKeyFile,
+		ClientCA:   caCertFile,
+	})
+	c.Assert(err, IsNil)
+	h.POST("/", func(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go h.ServiceJob()(ctx)
+
+	rootPool := x509.NewCertPool()
+	rootPool.AppendCertsFromPEM(caPEM)
+
+	dial := func(certFile, keyFile string) error {
+		var certs []tls.Certificate
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			c.Assert(err, IsNil)
+			certs = []tls.Certificate{cert}
+		}
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      rootPool,
+					Certificates: certs,
+				},
+			},
+		}
+		_, err := client.Post("https://"+h.listener.Addr().String()+"/", "text/plain", nil)
+		return err
+	}
+
+	c.Assert(dial(goodClientCertFile, goodClientKeyFile), IsNil)
+	c.Assert(dial(badClientCertFile, badClientKeyFile), NotNil)
+	c.Assert(dial("", ""), NotNil)
+}
+
+// TestEnsureCertGeneratesSelfSignedFallback checks that a server built
+// with no CertFile/KeyFile can still serve HTTPS once EnsureCert has run,
+// and that it reuses rather than regenerates the keypair on a second call
+// (e.g. across a plugin restart).
+func (s *HTTPSuite) TestEnsureCertGeneratesSelfSignedFallback(c *C) {
+	dir, err := ioutil.TempDir("", "ensure-cert")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	pathPrefix := dir + "/server"
+
+	h, err := NewHTTP(HTTPConfig{ListenAddr: "127.0.0.1:0"})
+	c.Assert(err, IsNil)
+	c.Assert(h.EnsureCert(pathPrefix), IsNil)
+
+	h.POST("/", func(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go h.ServiceJob()(ctx)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	resp, err := client.Post("https://"+h.listener.Addr().String()+"/", "text/plain", nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Body.Close(), IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+
+	certBytes, err := ioutil.ReadFile(pathPrefix + ".crt")
+	c.Assert(err, IsNil)
+
+	h2, err := NewHTTP(HTTPConfig{ListenAddr: "127.0.0.1:0"})
+	c.Assert(err, IsNil)
+	c.Assert(h2.EnsureCert(pathPrefix), IsNil)
+	certBytes2, err := ioutil.ReadFile(pathPrefix + ".crt")
+	c.Assert(err, IsNil)
+	c.Assert(certBytes2, DeepEquals, certBytes)
+}
+
+// TestEnsureCertNoopWithConfiguredKeypair checks that EnsureCert doesn't
+// touch pathPrefix at all when HTTPConfig already supplies a keypair,
+// since NewHTTP has already loaded it.
+func (s *HTTPSuite) TestEnsureCertNoopWithConfiguredKeypair(c *C) {
+	caCertFile, caKeyFile, caCert, caKey := genCert(c, nil, nil, true)
+	defer os.Remove(caCertFile)
+	defer os.Remove(caKeyFile)
+	serverCertFile, serverKeyFile, _, _ := genCert(c, caCert, caKey, false)
+	defer os.Remove(serverCertFile)
+	defer os.Remove(serverKeyFile)
+
+	h, err := NewHTTP(HTTPConfig{
+		ListenAddr: "127.0.0.1:0",
+		CertFile:   serverCertFile,
+		KeyFile:    serverKeyFile,
+	})
+	c.Assert(err, IsNil)
+
+	dir, err := ioutil.TempDir("", "ensure-cert-noop")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	pathPrefix := dir + "/server"
+
+	c.Assert(h.EnsureCert(pathPrefix), IsNil)
+	_, err = os.Stat(pathPrefix + ".crt")
+	c.Assert(os.IsNotExist(err), Equals, true)
+}